@@ -0,0 +1,225 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ADFNode is a node of an Atlassian Document Format document, as used by the JIRA Cloud REST API v3
+// See https://developer.atlassian.com/cloud/jira/platform/apis/document/structure/
+type ADFNode struct {
+	Type    string                 `json:"type"`
+	Version int                    `json:"version,omitempty"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []*ADFNode             `json:"content,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Marks   []*ADFMark             `json:"marks,omitempty"`
+}
+
+// ADFMark formats text nodes, e.g. strong or link
+type ADFMark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+var (
+	adfPanelPattern = regexp.MustCompile(`^\{(panel|info|note|tip|warning)(:[^}]*)?\}$`)
+	// The alternatives are tried in this order: link, color, bold, result with icon, icon
+	adfInlinePattern = regexp.MustCompile(`\[([^\[\]|]+)\|([^\[\]]+)\]|\{color:([^}]+)\}(.*?)\{color\}|\*([^*\s][^*\n]*?)\*|(\w+) \(([/!x])\)|\(([/!x])\)`)
+)
+
+// JIRA wiki macros and the ADF panel types they become
+var adfPanelTypes = map[string]string{
+	"panel":   "info",
+	"info":    "info",
+	"note":    "note",
+	"tip":     "success",
+	"warning": "warning",
+}
+
+// Icons become status lozenges when they follow a result (e.g. fail (x)) and emojis otherwise
+var adfIcons = map[string]struct{ color, emoji string }{
+	"/": {"green", ":check_mark:"},
+	"!": {"yellow", ":warning:"},
+	"x": {"red", ":cross_mark:"},
+}
+
+// ADF only supports hex colors for text
+var adfTextColors = map[string]string{
+	"red":    "#de350b",
+	"green":  "#00875a",
+	"yellow": "#ff991f",
+	"orange": "#ff991f",
+	"blue":   "#0052cc",
+	"grey":   "#6b778c",
+	"gray":   "#6b778c",
+}
+
+// Converts the subset of JIRA wiki markup the jira-service writes (headings, tables, panels, bold, links, colors, icons)
+// to an ADF document. Everything else is kept as plain text
+func convertWikiMarkupToADF(text string) *ADFNode {
+	return &ADFNode{Type: "doc", Version: 1, Content: parseADFBlocks(strings.Split(text, "\n"))}
+}
+
+func parseADFBlocks(lines []string) []*ADFNode {
+	blocks := []*ADFNode{}
+	var paragraph *ADFNode
+	var table *ADFNode
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		trimmed := strings.TrimSpace(line)
+
+		// Rows of a table follow each other, anything else ends the table
+		if strings.HasPrefix(trimmed, "|") {
+			paragraph = nil
+			if table == nil {
+				table = &ADFNode{Type: "table", Content: []*ADFNode{}}
+				blocks = append(blocks, table)
+			}
+			table.Content = append(table.Content, parseADFTableRow(trimmed))
+			continue
+		}
+		table = nil
+
+		if trimmed == "" {
+			paragraph = nil
+			continue
+		}
+
+		if match := wikiHeadingPattern.FindStringSubmatch(trimmed); match != nil {
+			paragraph = nil
+			level, _ := strconv.Atoi(match[1])
+			blocks = append(blocks, &ADFNode{
+				Type:    "heading",
+				Attrs:   map[string]interface{}{"level": level},
+				Content: parseADFInline(strings.TrimPrefix(trimmed, match[0]), nil),
+			})
+			continue
+		}
+
+		// Panels are {info}...{info}, the content is parsed as blocks on its own
+		if match := adfPanelPattern.FindStringSubmatch(trimmed); match != nil {
+			paragraph = nil
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "{"+match[1]+"}" {
+				end++
+			}
+			blocks = append(blocks, &ADFNode{
+				Type:    "panel",
+				Attrs:   map[string]interface{}{"panelType": adfPanelTypes[match[1]]},
+				Content: parseADFBlocks(lines[i+1 : end]),
+			})
+			i = end
+			continue
+		}
+
+		// Consecutive lines are one paragraph with line breaks
+		if paragraph == nil {
+			paragraph = &ADFNode{Type: "paragraph", Content: []*ADFNode{}}
+			blocks = append(blocks, paragraph)
+		} else {
+			paragraph.Content = append(paragraph.Content, &ADFNode{Type: "hardBreak"})
+		}
+		paragraph.Content = append(paragraph.Content, parseADFInline(line, nil)...)
+	}
+
+	// Panels must not be empty
+	if len(blocks) == 0 {
+		blocks = append(blocks, &ADFNode{Type: "paragraph", Content: []*ADFNode{}})
+	}
+
+	return blocks
+}
+
+// Header rows are ||a||b||, other rows are |a|b|
+func parseADFTableRow(line string) *ADFNode {
+	cellType := "tableCell"
+	separator := "|"
+	if strings.HasPrefix(line, "||") {
+		cellType = "tableHeader"
+		separator = "||"
+	}
+
+	row := &ADFNode{Type: "tableRow", Content: []*ADFNode{}}
+	for _, cell := range splitWikiTableRow(strings.TrimSuffix(strings.TrimPrefix(line, separator), separator), separator) {
+		row.Content = append(row.Content, &ADFNode{
+			Type: cellType,
+			Content: []*ADFNode{
+				{Type: "paragraph", Content: parseADFInline(cell, nil)},
+			},
+		})
+	}
+	return row
+}
+
+// Parses links, colors, bold text and icons, marks are passed down to nested text
+func parseADFInline(text string, marks []*ADFMark) []*ADFNode {
+	nodes := []*ADFNode{}
+
+	for text != "" {
+		match := adfInlinePattern.FindStringSubmatchIndex(text)
+		if match == nil {
+			nodes = append(nodes, newADFText(text, marks))
+			break
+		}
+
+		if match[0] > 0 {
+			nodes = append(nodes, newADFText(text[:match[0]], marks))
+		}
+
+		group := func(n int) string {
+			if match[2*n] < 0 {
+				return ""
+			}
+			return text[match[2*n]:match[2*n+1]]
+		}
+
+		switch {
+		case match[2] >= 0: // [text|url]
+			linkMarks := append(append([]*ADFMark{}, marks...), &ADFMark{Type: "link", Attrs: map[string]interface{}{"href": group(2)}})
+			nodes = append(nodes, newADFText(group(1), linkMarks))
+		case match[6] >= 0: // {color:red}text{color}
+			colorMarks := marks
+			if color := getADFTextColor(group(3)); color != "" {
+				colorMarks = append(append([]*ADFMark{}, marks...), &ADFMark{Type: "textColor", Attrs: map[string]interface{}{"color": color}})
+			}
+			nodes = append(nodes, parseADFInline(group(4), colorMarks)...)
+		case match[10] >= 0: // *bold*
+			nodes = append(nodes, parseADFInline(group(5), append(append([]*ADFMark{}, marks...), &ADFMark{Type: "strong"}))...)
+		case match[12] >= 0: // fail (x)
+			nodes = append(nodes, &ADFNode{
+				Type:  "status",
+				Attrs: map[string]interface{}{"text": strings.ToUpper(group(6)), "color": adfIcons[group(7)].color},
+			})
+		default: // (x)
+			nodes = append(nodes, &ADFNode{
+				Type:  "emoji",
+				Attrs: map[string]interface{}{"shortName": adfIcons[group(8)].emoji, "text": "(" + group(8) + ")"},
+			})
+		}
+
+		text = text[match[1]:]
+	}
+
+	// Text nodes must not be empty
+	result := []*ADFNode{}
+	for _, node := range nodes {
+		if node.Type != "text" || node.Text != "" {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func newADFText(text string, marks []*ADFMark) *ADFNode {
+	return &ADFNode{Type: "text", Text: text, Marks: marks}
+}
+
+func getADFTextColor(color string) string {
+	if strings.HasPrefix(color, "#") {
+		return color
+	}
+	return adfTextColors[strings.ToLower(color)]
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func adfDoc(content ...*ADFNode) *ADFNode {
+	return &ADFNode{Type: "doc", Version: 1, Content: content}
+}
+
+func adfParagraph(content ...*ADFNode) *ADFNode {
+	return &ADFNode{Type: "paragraph", Content: content}
+}
+
+func adfText(text string, marks ...*ADFMark) *ADFNode {
+	return &ADFNode{Type: "text", Text: text, Marks: marks}
+}
+
+func adfCell(cellType string, content ...*ADFNode) *ADFNode {
+	return &ADFNode{Type: cellType, Content: []*ADFNode{adfParagraph(content...)}}
+}
+
+func adfRow(cells ...*ADFNode) *ADFNode {
+	return &ADFNode{Type: "tableRow", Content: cells}
+}
+
+func adfColor(color string) *ADFMark {
+	return &ADFMark{Type: "textColor", Attrs: map[string]interface{}{"color": color}}
+}
+
+var adfStrong = &ADFMark{Type: "strong"}
+
+func TestConvertWikiMarkupToADF(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *ADFNode
+	}{
+		{
+			name: "empty text",
+			text: "",
+			want: adfDoc(adfParagraph()),
+		},
+		{
+			name: "lines become one paragraph with hard breaks, blank lines start a new one",
+			text: "first\nsecond\n\nthird",
+			want: adfDoc(
+				adfParagraph(adfText("first"), &ADFNode{Type: "hardBreak"}, adfText("second")),
+				adfParagraph(adfText("third")),
+			),
+		},
+		{
+			name: "heading",
+			text: "h4. Root *Cause*",
+			want: adfDoc(&ADFNode{
+				Type:    "heading",
+				Attrs:   map[string]interface{}{"level": 4},
+				Content: []*ADFNode{adfText("Root "), adfText("Cause", adfStrong)},
+			}),
+		},
+		{
+			name: "table with header row",
+			text: "||*Result*||*Score*||\n|pass|100|",
+			want: adfDoc(&ADFNode{Type: "table", Content: []*ADFNode{
+				adfRow(adfCell("tableHeader", adfText("Result", adfStrong)), adfCell("tableHeader", adfText("Score", adfStrong))),
+				adfRow(adfCell("tableCell", adfText("pass")), adfCell("tableCell", adfText("100"))),
+			}}),
+		},
+		{
+			name: "link inside of a table cell",
+			text: "|[Bridge|https://bridge.example.com]|done|",
+			want: adfDoc(&ADFNode{Type: "table", Content: []*ADFNode{
+				adfRow(
+					adfCell("tableCell", adfText("Bridge", &ADFMark{Type: "link", Attrs: map[string]interface{}{"href": "https://bridge.example.com"}})),
+					adfCell("tableCell", adfText("done")),
+				),
+			}}),
+		},
+		{
+			name: "link with pipe in the url",
+			text: "[Link To Keptn's Bridge|https://bridge.example.com/?filter=a|b]",
+			want: adfDoc(adfParagraph(
+				adfText("Link To Keptn's Bridge", &ADFMark{Type: "link", Attrs: map[string]interface{}{"href": "https://bridge.example.com/?filter=a|b"}}),
+			)),
+		},
+		{
+			name: "results with icon become status lozenges",
+			text: "pass (/) warning (!) fail (x)",
+			want: adfDoc(adfParagraph(
+				&ADFNode{Type: "status", Attrs: map[string]interface{}{"text": "PASS", "color": "green"}},
+				adfText(" "),
+				&ADFNode{Type: "status", Attrs: map[string]interface{}{"text": "WARNING", "color": "yellow"}},
+				adfText(" "),
+				&ADFNode{Type: "status", Attrs: map[string]interface{}{"text": "FAIL", "color": "red"}},
+			)),
+		},
+		{
+			name: "icons without result become emojis",
+			text: "(x)",
+			want: adfDoc(adfParagraph(
+				&ADFNode{Type: "emoji", Attrs: map[string]interface{}{"shortName": ":cross_mark:", "text": "(x)"}},
+			)),
+		},
+		{
+			name: "bold inside of color",
+			text: "{color:red}*response time*{color}",
+			want: adfDoc(adfParagraph(adfText("response time", adfColor("#de350b"), adfStrong))),
+		},
+		{
+			name: "color inside of bold",
+			text: "*{color:#ff0000}response time{color}*",
+			want: adfDoc(adfParagraph(adfText("response time", adfStrong, adfColor("#ff0000")))),
+		},
+		{
+			name: "unknown color is dropped",
+			text: "{color:purple}text{color}",
+			want: adfDoc(adfParagraph(adfText("text"))),
+		},
+		{
+			name: "panel content is parsed as blocks",
+			text: "{info:title=Details}\n*one*\ntwo\n{info}\nafter",
+			want: adfDoc(
+				&ADFNode{
+					Type:    "panel",
+					Attrs:   map[string]interface{}{"panelType": "info"},
+					Content: []*ADFNode{adfParagraph(adfText("one", adfStrong), &ADFNode{Type: "hardBreak"}, adfText("two"))},
+				},
+				adfParagraph(adfText("after")),
+			),
+		},
+		{
+			name: "tip panel",
+			text: "{tip}\nok\n{tip}",
+			want: adfDoc(&ADFNode{
+				Type:    "panel",
+				Attrs:   map[string]interface{}{"panelType": "success"},
+				Content: []*ADFNode{adfParagraph(adfText("ok"))},
+			}),
+		},
+		{
+			name: "empty panel gets an empty paragraph",
+			text: "{warning}\n{warning}",
+			want: adfDoc(&ADFNode{
+				Type:    "panel",
+				Attrs:   map[string]interface{}{"panelType": "warning"},
+				Content: []*ADFNode{adfParagraph()},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Compare the JSON which is sent to JIRA, so that nil and empty content are the same
+			got, err := json.Marshal(convertWikiMarkupToADF(tt.text))
+			if err != nil {
+				t.Fatalf("could not encode ADF: %s", err)
+			}
+			want, err := json.Marshal(tt.want)
+			if err != nil {
+				t.Fatalf("could not encode expected ADF: %s", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("convertWikiMarkupToADF(%q)\n got: %s\nwant: %s", tt.text, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2" // make sure to use v2 cloudevents here
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// The state of an approval is kept in the labels of its ticket, so the jira-service doesn't need to store anything
+const (
+	approvalPendingLabel           = "keptn_approval:pending"
+	approvalApprovedLabel          = "keptn_approval:approved"
+	approvalRejectedLabel          = "keptn_approval:rejected"
+	approvalContextLabelPrefix     = "keptn_approval_context:"
+	approvalTriggeredIDLabelPrefix = "keptn_triggeredid:"
+)
+
+// Opens an approval ticket for manual approvals. The approval.finished event is sent
+// once the ticket is moved to the approved or rejected status (see handleApprovalStatusChange)
+func HandleApprovalTriggeredEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *keptnv2.ApprovalTriggeredEventData, details *JiraDetails) error {
+	log.Printf("[approval.go] Handling approval.triggered event: %s", incomingEvent.Context.GetID())
+
+	if !details.TicketForApprovals {
+		log.Println("[approval.go] TicketForApprovals flag is set to false. Got an approval.triggered from Keptn but doing nothing. If you want a ticket, set flag to true")
+		return nil
+	}
+
+	if !isManualApproval(data) {
+		log.Println("[approval.go] Approval strategy is automatic for result " + string(data.Result) + ". Leaving it to Keptn")
+		return nil
+	}
+
+	// Only JIRA webhooks finish approvals, a ticket in another tracker would keep the sequence waiting forever
+	if !isJIRATracker(details) {
+		log.Println("[approval.go] Approvals are only supported with JIRA, tracker is " + details.Tracker + ". Leaving the approval to Keptn's Bridge")
+		return nil
+	}
+
+	if _, err := myKeptn.SendTaskStartedEvent(&keptnv2.ApprovalStartedEventData{}, ServiceName); err != nil {
+		return fmt.Errorf("could not send approval.started event: %w", err)
+	}
+
+	ticket, err := createJIRATicketForApproval(myKeptn, incomingEvent, data, details)
+	if isTicketQueuedError(err) {
+		// The labels of the ticket hold everything needed to finish the approval, so it works the same once the ticket exists
+		log.Println("[approval.go] Approval ticket will be created later on, the approval stays open until then:", err)
+		return nil
+	}
+	if err != nil {
+		finishedEventData := &keptnv2.ApprovalFinishedEventData{
+			EventData: keptnv2.EventData{
+				Status:  keptnv2.StatusErrored,
+				Result:  keptnv2.ResultFailed,
+				Message: err.Error(),
+			},
+		}
+		if _, sendErr := myKeptn.SendTaskFinishedEvent(finishedEventData, ServiceName); sendErr != nil {
+			log.Println("[approval.go] Could not send approval.finished event:", sendErr)
+		}
+		return err
+	}
+
+	log.Println("[approval.go] Waiting for approval ticket " + ticket.Key + " to be moved to " + details.ApprovedStatus + " or " + details.RejectedStatus)
+	return nil
+}
+
+// Only manual approvals need a human, automatic ones are handled by Keptn itself
+func isManualApproval(data *keptnv2.ApprovalTriggeredEventData) bool {
+	if data.Result == keptnv2.ResultWarning {
+		return data.Approval.Warning == keptnv2.ApprovalManual
+	}
+	return data.Approval.Pass == keptnv2.ApprovalManual
+}
+
+func createJIRATicketForApproval(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *keptnv2.ApprovalTriggeredEventData, details *JiraDetails) (*Ticket, error) {
+
+	log.Println("[approval.go] Creating JIRA Body details for approval...")
+
+	// Build summary field (JIRA ticket title)
+	summary := "[APPROVAL] " + data.EventData.GetProject() + " - " + data.EventData.GetService() + " - " + data.EventData.GetStage() + " - Result: " + string(data.Result)
+
+	description := "||*Result*||*Project*||*Service*||*Stage*||\n"
+	description += "|" + getResultWithIcon(string(data.Result)) + "|" + data.EventData.GetProject() + "|" + data.EventData.GetService() + "|" + data.EventData.GetStage() + "|\n\n"
+
+	description += "Move this ticket to *" + details.ApprovedStatus + "* to continue the sequence or to *" + details.RejectedStatus + "* to abort it.\n\n"
+
+	// Add Keptn Context
+	description += "Keptn Context ID: " + myKeptn.KeptnContext + "\n"
+
+	// Add link to Keptn Bridge
+	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + data.EventData.GetProject() + "/sequence/" + myKeptn.KeptnContext
+	description += "[Link To Keptn's Bridge|" + bridgeURL + "]"
+
+	// Apply user supplied templates, if any
+	templateData := newTicketTemplateData(myKeptn, data, summary, description)
+	summary, description = renderTicketTemplate(keptnv2.ApprovalTaskName, templateData, details)
+
+	// Everything that is needed to send the approval.finished event later on is stored in the labels
+	labels := createJIRALabelsForEventData(&data.EventData)
+	labels = append(labels, approvalPendingLabel)
+	labels = append(labels, approvalContextLabelPrefix+myKeptn.KeptnContext)
+	labels = append(labels, approvalTriggeredIDLabelPrefix+incomingEvent.ID())
+
+	tracker, err := newTracker(details)
+	if err != nil {
+		return nil, err
+	}
+
+	// Approvals always get their own ticket, even if there is already a ticket for the Keptn context
+	ticket := &Ticket{
+		Summary:      summary,
+		Description:  description,
+		Labels:       labels,
+		CustomFields: getJIRACustomFields(templateData, details),
+		Attachments:  newEventAttachments(myKeptn, details),
+	}
+	setTicketComponentsAndVersions(ticket, templateData, details)
+	routeTicket(ticket, templateData, details)
+	resolveTicketAssignee(ticket, templateData, details)
+
+	return createNewTicket(tracker, ticket, details)
+}
+
+// Sends approval.finished if a pending approval ticket was moved to the approved or rejected status
+// The webhook only tells which ticket changed, status and labels are read from JIRA so a forged webhook can't finish an approval
+func handleApprovalStatusChange(webhookEvent *JiraWebhookEvent) {
+	issue := webhookEvent.Issue
+	if issue.Fields == nil || issue.Fields.Status == nil || !hasJIRALabel(issue.Fields.Labels, approvalPendingLabel) {
+		return
+	}
+
+	// Webhooks only come from JIRA, the settings from the environment are enough to read the ticket
+	tracker, err := newTrackerByName(trackerJIRA)
+	if err != nil {
+		log.Println("[approval.go] Could not create tracker client:", err)
+		return
+	}
+	ticket, err := tracker.GetTicket(issue.Key)
+	if err != nil {
+		log.Println("[approval.go] Could not get approval ticket "+issue.Key+":", err)
+		return
+	}
+	if !hasJIRALabel(ticket.Labels, approvalPendingLabel) {
+		log.Println("[approval.go] Ticket " + ticket.Key + " is no pending approval (anymore)")
+		return
+	}
+
+	keptnContext := getJIRALabelValue(ticket.Labels, approvalContextLabelPrefix)
+	triggeredID := getJIRALabelValue(ticket.Labels, approvalTriggeredIDLabelPrefix)
+	if keptnContext == "" || triggeredID == "" {
+		log.Println("[approval.go] Approval ticket " + ticket.Key + " is missing the Keptn context or triggered ID label")
+		return
+	}
+
+	eventData := getKeptnEventDataFromJIRALabels(ticket.Labels)
+
+	myKeptn, err := newKeptnHandlerForTriggeredEvent(keptnv2.ApprovalTaskName, keptnContext, triggeredID, eventData)
+	if err != nil {
+		log.Println("[approval.go] Could not create Keptn Handler:", err)
+		return
+	}
+
+	// The approved and rejected status might be overridden in jira.yaml
+	details := loadJIRADetails(myKeptn)
+
+	status := ticket.Status
+	finishedEventData := &keptnv2.ApprovalFinishedEventData{
+		EventData: keptnv2.EventData{
+			Status: keptnv2.StatusSucceeded,
+		},
+	}
+	newLabel := ""
+	if strings.EqualFold(status, details.ApprovedStatus) {
+		finishedEventData.Result = keptnv2.ResultPass
+		newLabel = approvalApprovedLabel
+	} else if strings.EqualFold(status, details.RejectedStatus) {
+		finishedEventData.Result = keptnv2.ResultFailed
+		newLabel = approvalRejectedLabel
+	} else {
+		return
+	}
+
+	finishedEventData.Message = "Moved to " + status + " in JIRA ticket " + ticket.Key
+	if webhookEvent.User != nil && webhookEvent.User.DisplayName != "" {
+		finishedEventData.Message += " by " + webhookEvent.User.DisplayName
+	}
+
+	if _, err := myKeptn.SendTaskFinishedEvent(finishedEventData, ServiceName); err != nil {
+		log.Println("[approval.go] Could not send approval.finished event:", err)
+		return
+	}
+	log.Println("[approval.go] Sent approval.finished with result " + string(finishedEventData.Result) + " for ticket " + ticket.Key)
+
+	// Mark the approval as done, so that further status changes are ignored
+	if err := tracker.UpdateLabels(ticket.Key, []string{newLabel}, []string{approvalPendingLabel}); err != nil {
+		log.Println("[approval.go] Could not update labels of ticket", ticket.Key, ":", err)
+	}
+	addTicketComment(tracker, ticket.Key, "Keptn approval finished with result: "+getResultWithIcon(string(finishedEventData.Result)))
+}
+
+// Sends approval.finished with status errored for an approval ticket which could not be created, e.g. when the outbox gave up on it
+func finishApprovalWithoutTicket(ticket *Ticket, reason string) {
+	keptnContext := getJIRALabelValue(ticket.Labels, approvalContextLabelPrefix)
+	triggeredID := getJIRALabelValue(ticket.Labels, approvalTriggeredIDLabelPrefix)
+	if keptnContext == "" || triggeredID == "" {
+		log.Println("[approval.go] Approval ticket is missing the Keptn context or triggered ID label, can't finish the approval")
+		return
+	}
+
+	myKeptn, err := newKeptnHandlerForTriggeredEvent(keptnv2.ApprovalTaskName, keptnContext, triggeredID, getKeptnEventDataFromJIRALabels(ticket.Labels))
+	if err != nil {
+		log.Println("[approval.go] Could not create Keptn Handler:", err)
+		return
+	}
+
+	finishedEventData := &keptnv2.ApprovalFinishedEventData{
+		EventData: keptnv2.EventData{
+			Status:  keptnv2.StatusErrored,
+			Result:  keptnv2.ResultFailed,
+			Message: "Could not create approval ticket: " + reason,
+		},
+	}
+	if _, err := myKeptn.SendTaskFinishedEvent(finishedEventData, ServiceName); err != nil {
+		log.Println("[approval.go] Could not send approval.finished event:", err)
+		return
+	}
+	log.Println("[approval.go] Sent approval.finished with status errored for Keptn context " + keptnContext)
+}
+
+// Builds a Keptn Handler for a .triggered event which was received earlier, so that .finished can be sent for it
+func newKeptnHandlerForTriggeredEvent(taskName string, keptnContext string, triggeredID string, eventData *keptnv2.EventData) (*keptnv2.Keptn, error) {
+	event := cloudevents.NewEvent()
+	event.SetID(triggeredID)
+	event.SetType(keptnv2.GetTriggeredEventType(taskName))
+	event.SetSource(ServiceName)
+	event.SetExtension("shkeptncontext", keptnContext)
+	if err := event.SetData(cloudevents.ApplicationJSON, eventData); err != nil {
+		return nil, err
+	}
+
+	return keptnv2.NewKeptn(&event, keptnOptions)
+}
+
+func hasJIRALabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the value of the first label with the given prefix, e.g. keptn_project:
+func getJIRALabelValue(labels []string, prefix string) string {
+	for _, label := range labels {
+		if strings.HasPrefix(label, prefix) {
+			return strings.TrimPrefix(label, prefix)
+		}
+	}
+	return ""
+}
+
+// Status changes arrive as jira:issue_updated events with a status item in the changelog
+func isJIRAStatusChange(webhookEvent *JiraWebhookEvent) bool {
+	if webhookEvent.Changelog == nil {
+		return false
+	}
+	for _, item := range webhookEvent.Changelog.Items {
+		if item.Field == "status" {
+			return true
+		}
+	}
+	return false
+}
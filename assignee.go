@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prefixes of assignee entries which are resolved at ticket time, entries without prefix are used as they are
+const (
+	assigneeEmailPrefix  = "email:"
+	assigneeLabelPrefix  = "label:"
+	assigneeOnCallPrefix = "oncall:"
+)
+
+// Resolved users are kept for this long, unless JIRA_USER_CACHE_TTL is set
+const defaultUserCacheTTL = time.Hour
+
+// userFinder is implemented by trackers which can look up users by email address
+type userFinder interface {
+	// FindUser returns the ID of the user with the email address, or an empty string if there is no such user
+	FindUser(email string) (string, error)
+}
+
+// OnCallSchedule is the content of an on-call rotation file
+type OnCallSchedule struct {
+	Rotations []OnCallRotation `yaml:"rotations"`
+}
+
+// OnCallRotation assigns tickets to a user from Start until End
+type OnCallRotation struct {
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+	// Assignee is an account ID, a username or an email address
+	Assignee string `yaml:"assignee"`
+}
+
+type cachedUser struct {
+	id      string
+	expires time.Time
+}
+
+type cachedOnCallSchedule struct {
+	schedule *OnCallSchedule
+	modTime  time.Time
+}
+
+var userCache = map[string]cachedUser{}
+var onCallScheduleCache = map[string]cachedOnCallSchedule{}
+var assigneeCacheMutex sync.Mutex
+
+// Resolves the assignee of the ticket, which comes from a routing rule or the default assignee
+// The assignee is a comma separated list of entries which are tried in order, the first entry that resolves to a user wins:
+//
+//	email:joe@example.com    looks up the user in JIRA
+//	label:owner              takes the user from the label of the event
+//	oncall:/path/to/file     takes the user who is on call right now
+//	5b10ac8d82e05b22cc7d4ef5 is used as it is, so it should be the last entry as default
+//
+// If no entry of a routing rule can be resolved, the default assignee is tried. The ticket stays unassigned if that fails as well
+func resolveTicketAssignee(ticket *Ticket, templateData *TicketTemplateData, details *JiraDetails) {
+	assignee := ticket.Assignee
+	ticket.Assignee = ""
+
+	if assignee != "" {
+		ticket.Assignee = resolveAssignee(assignee, templateData, details)
+		if ticket.Assignee != "" {
+			return
+		}
+		log.Println("[assignee.go] None of the assignees " + assignee + " could be resolved, trying the default assignee")
+	}
+
+	defaultAssignee := getDefaultAssignee(details)
+	if defaultAssignee == "" || defaultAssignee == assignee {
+		return
+	}
+	ticket.Assignee = resolveAssignee(defaultAssignee, templateData, details)
+	if ticket.Assignee == "" {
+		log.Println("[assignee.go] None of the default assignees " + defaultAssignee + " could be resolved, leaving ticket unassigned")
+	}
+}
+
+// Returns the user of the first entry of the comma separated list that can be resolved, or an empty string
+func resolveAssignee(assignee string, templateData *TicketTemplateData, details *JiraDetails) string {
+	for _, entry := range strings.Split(assignee, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		user, err := resolveAssigneeEntry(entry, templateData, details)
+		if err != nil {
+			log.Println("[assignee.go] Could not resolve assignee "+entry+":", err)
+			continue
+		}
+		if user != "" {
+			return user
+		}
+	}
+	return ""
+}
+
+func getDefaultAssignee(details *JiraDetails) string {
+	if strings.EqualFold(details.Tracker, trackerGitHub) {
+		return details.GitHub.Assignee
+	}
+	return details.AssigneeID
+}
+
+func resolveAssigneeEntry(entry string, templateData *TicketTemplateData, details *JiraDetails) (string, error) {
+	switch {
+	case strings.HasPrefix(entry, assigneeEmailPrefix):
+		return findUserByEmail(strings.TrimPrefix(entry, assigneeEmailPrefix), details)
+	case strings.HasPrefix(entry, assigneeLabelPrefix):
+		labels, _ := templateData.Event["labels"].(map[string]interface{})
+		label, ok := labels[strings.TrimPrefix(entry, assigneeLabelPrefix)]
+		if !ok || fmt.Sprint(label) == "" {
+			return "", nil
+		}
+		return resolveUser(fmt.Sprint(label), details)
+	case strings.HasPrefix(entry, assigneeOnCallPrefix):
+		user, err := getOnCallUser(strings.TrimPrefix(entry, assigneeOnCallPrefix), time.Now())
+		if err != nil || user == "" {
+			return "", err
+		}
+		return resolveUser(user, details)
+	}
+	return entry, nil
+}
+
+// Labels and on-call schedules can contain email addresses as well as IDs
+func resolveUser(user string, details *JiraDetails) (string, error) {
+	if strings.Contains(user, "@") {
+		return findUserByEmail(user, details)
+	}
+	return user, nil
+}
+
+// Looks up the user through the tracker, results (including unknown users) are cached
+func findUserByEmail(email string, details *JiraDetails) (string, error) {
+	key := strings.ToLower(details.Tracker + "|" + details.BaseURL + "|" + email)
+
+	assigneeCacheMutex.Lock()
+	cached, ok := userCache[key]
+	assigneeCacheMutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.id, nil
+	}
+
+	tracker, err := newTracker(details)
+	if err != nil {
+		return "", err
+	}
+	finder, ok := tracker.(userFinder)
+	if !ok {
+		return "", fmt.Errorf("%s can't look up users by email address", tracker.Name())
+	}
+
+	id, err := finder.FindUser(email)
+	if err != nil {
+		return "", err
+	}
+
+	assigneeCacheMutex.Lock()
+	userCache[key] = cachedUser{id: id, expires: time.Now().Add(parseDurationOrDefault(os.Getenv("JIRA_USER_CACHE_TTL"), defaultUserCacheTTL))}
+	assigneeCacheMutex.Unlock()
+
+	if id == "" {
+		log.Println("[assignee.go] No user found for " + email)
+	}
+	return id, nil
+}
+
+// Returns the assignee of the first rotation which covers the time, or an empty string if nobody is on call
+func getOnCallUser(path string, now time.Time) (string, error) {
+	schedule, err := loadOnCallSchedule(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rotation := range schedule.Rotations {
+		if !now.Before(rotation.Start) && now.Before(rotation.End) {
+			return rotation.Assignee, nil
+		}
+	}
+	log.Println("[assignee.go] Nobody is on call in " + path)
+	return "", nil
+}
+
+// The schedule is read again once the file changes
+func loadOnCallSchedule(path string) (*OnCallSchedule, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	assigneeCacheMutex.Lock()
+	cached, ok := onCallScheduleCache[path]
+	assigneeCacheMutex.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.schedule, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &OnCallSchedule{}
+	if err := yaml.Unmarshal(content, schedule); err != nil {
+		return nil, err
+	}
+
+	assigneeCacheMutex.Lock()
+	onCallScheduleCache[path] = cachedOnCallSchedule{schedule: schedule, modTime: info.ModTime()}
+	assigneeCacheMutex.Unlock()
+	return schedule, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveTicketAssignee(t *testing.T) {
+	now := time.Now()
+	onCallFile := filepath.Join(t.TempDir(), "oncall.yaml")
+	schedule := "rotations:\n" +
+		"  - start: " + now.Add(-time.Hour).Format(time.RFC3339) + "\n" +
+		"    end: " + now.Add(time.Hour).Format(time.RFC3339) + "\n" +
+		"    assignee: on-call-user\n"
+	if err := ioutil.WriteFile(onCallFile, []byte(schedule), 0600); err != nil {
+		t.Fatalf("could not write on-call schedule: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		// assignee is set on the ticket by a routing rule
+		assignee        string
+		defaultAssignee string
+		tracker         string
+		labels          map[string]interface{}
+		want            string
+	}{
+		{
+			name:            "routing rule wins over the default assignee",
+			assignee:        "rule-user",
+			defaultAssignee: "default-user",
+			want:            "rule-user",
+		},
+		{
+			name:            "default assignee without routing rule",
+			defaultAssignee: "default-user",
+			want:            "default-user",
+		},
+		{
+			name:            "label of the event",
+			assignee:        "label:owner",
+			defaultAssignee: "default-user",
+			labels:          map[string]interface{}{"owner": "owner-user"},
+			want:            "owner-user",
+		},
+		{
+			name:            "user on call",
+			assignee:        "oncall:" + onCallFile,
+			defaultAssignee: "default-user",
+			want:            "on-call-user",
+		},
+		{
+			name:            "unresolvable entry is skipped",
+			assignee:        "label:owner, backup-user",
+			defaultAssignee: "default-user",
+			labels:          map[string]interface{}{"team": "carts"},
+			want:            "backup-user",
+		},
+		{
+			name:            "unresolvable routing rule falls back to the default assignee",
+			assignee:        "label:owner, oncall:" + filepath.Join(filepath.Dir(onCallFile), "missing.yaml"),
+			defaultAssignee: "label:team, default-user",
+			labels:          map[string]interface{}{"owner": ""},
+			want:            "default-user",
+		},
+		{
+			name:            "email lookup without user search falls back to the default assignee",
+			assignee:        "email:joe@example.com",
+			defaultAssignee: "octocat",
+			tracker:         trackerGitHub,
+			want:            "octocat",
+		},
+		{
+			name:            "unresolvable default assignee leaves the ticket unassigned",
+			assignee:        "label:owner",
+			defaultAssignee: "label:team",
+			want:            "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := &JiraDetails{Tracker: tt.tracker, AssigneeID: tt.defaultAssignee}
+			details.GitHub.Token = "token"
+			details.GitHub.Repository = "keptn/carts"
+			details.GitHub.Assignee = tt.defaultAssignee
+
+			ticket := &Ticket{Assignee: tt.assignee}
+			templateData := &TicketTemplateData{Event: map[string]interface{}{"labels": tt.labels}}
+			resolveTicketAssignee(ticket, templateData, details)
+
+			if ticket.Assignee != tt.want {
+				t.Errorf("resolveTicketAssignee(%q) = %q, want %q", tt.assignee, ticket.Assignee, tt.want)
+			}
+		})
+	}
+}
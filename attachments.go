@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// Name of the SLO resource of a service in the Keptn configuration repo
+const sloResource = "slo.yaml"
+
+// TicketAttachment is a file which is uploaded to the ticket once it is created
+type TicketAttachment struct {
+	Name    string `json:"name"`
+	Content []byte `json:"content"`
+}
+
+// attachmentUploader is implemented by trackers which can attach files to tickets
+type attachmentUploader interface {
+	AddAttachment(key string, name string, content []byte) error
+}
+
+// Returns the raw CloudEvent as attachment, if JIRA_ATTACH_PAYLOADS is enabled
+func newEventAttachments(myKeptn *keptnv2.Keptn, details *JiraDetails) []TicketAttachment {
+	if !details.AttachPayloads || myKeptn.CloudEvent == nil {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(myKeptn.CloudEvent, "", "  ")
+	if err != nil {
+		log.Println("[attachments.go] Could not encode CloudEvent:", err)
+		return nil
+	}
+	return []TicketAttachment{{Name: "cloudevent.json", Content: content}}
+}
+
+// Adds the evaluation data and the SLO file of the service to the CloudEvent, which is everything needed to debug a quality gate
+func newEvaluationAttachments(myKeptn *keptnv2.Keptn, data *keptnv2.EvaluationFinishedEventData, details *JiraDetails) []TicketAttachment {
+	attachments := newEventAttachments(myKeptn, details)
+	if !details.AttachPayloads {
+		return attachments
+	}
+
+	if content, err := json.MarshalIndent(data, "", "  "); err != nil {
+		log.Println("[attachments.go] Could not encode evaluation:", err)
+	} else {
+		attachments = append(attachments, TicketAttachment{Name: "evaluation.json", Content: content})
+	}
+
+	if myKeptn.ResourceHandler == nil {
+		return attachments
+	}
+	resource, err := myKeptn.ResourceHandler.GetServiceResource(data.GetProject(), data.GetStage(), data.GetService(), sloResource)
+	if err != nil {
+		log.Println("[attachments.go] Could not get "+sloResource+" of service "+data.GetService()+":", err)
+		return attachments
+	}
+	return append(attachments, TicketAttachment{Name: sloResource, Content: []byte(resource.ResourceContent)})
+}
+
+// Uploads the attachments of the ticket, failures are logged as the ticket itself exists already
+func addTicketAttachments(tracker Tracker, key string, attachments []TicketAttachment) {
+	if len(attachments) == 0 {
+		return
+	}
+
+	uploader, ok := tracker.(attachmentUploader)
+	if !ok {
+		log.Println("[attachments.go] " + tracker.Name() + " doesn't support attachments, skipping them")
+		return
+	}
+
+	for _, attachment := range attachments {
+		if err := uploader.AddAttachment(key, attachment.Name, attachment.Content); err != nil {
+			log.Println("[attachments.go] Could not attach "+attachment.Name+" to ticket "+key+":", err)
+			continue
+		}
+		log.Println("[attachments.go] Attached " + attachment.Name + " to ticket " + key)
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Ways to authenticate against JIRA, selected with JIRA_AUTH_TYPE
+const (
+	// Username and API token (JIRA Cloud) or password (JIRA Server)
+	jiraAuthBasic = "basic"
+	// Personal access token of JIRA Server and Data Center 8.14+, JIRA_API_TOKEN holds the token
+	jiraAuthPAT = "pat"
+	// OAuth 2.0 with client credentials or a refresh token
+	jiraAuthOAuth2 = "oauth2"
+	// OAuth 1.0a with an RSA signed application link (JIRA Server and Data Center)
+	jiraAuthOAuth1 = "oauth1"
+)
+
+// Access tokens are refreshed this long before they expire
+const oauthTokenExpiryDelta = time.Minute
+
+// Token requests time out like the requests to the tracker, so that a stalled token endpoint doesn't block the event handling
+var oauthHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// OAuthDetails holds the settings of the OAuth auth types
+type OAuthDetails struct {
+	// OAuth 2.0
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// RefreshToken selects the refresh token grant, the client credentials grant is used otherwise
+	RefreshToken string
+	Scope        string
+	// TokenFile keeps the latest refresh token, as refresh tokens may be rotated on every refresh
+	TokenFile string
+
+	// OAuth 1.0a
+	ConsumerKey string
+	// PrivateKey is the PEM encoded RSA key of the application link
+	PrivateKey  string
+	AccessToken string
+}
+
+func setOAuthDetails() {
+	JIRA_DETAILS.OAuth.TokenURL = os.Getenv("JIRA_OAUTH_TOKEN_URL")
+	if JIRA_DETAILS.OAuth.TokenURL == "" {
+		JIRA_DETAILS.OAuth.TokenURL = "https://auth.atlassian.com/oauth/token"
+	}
+	JIRA_DETAILS.OAuth.ClientID = os.Getenv("JIRA_OAUTH_CLIENT_ID")
+	JIRA_DETAILS.OAuth.ClientSecret = os.Getenv("JIRA_OAUTH_CLIENT_SECRET")
+	JIRA_DETAILS.OAuth.RefreshToken = os.Getenv("JIRA_OAUTH_REFRESH_TOKEN")
+	JIRA_DETAILS.OAuth.Scope = os.Getenv("JIRA_OAUTH_SCOPE")
+	JIRA_DETAILS.OAuth.TokenFile = os.Getenv("JIRA_OAUTH_TOKEN_FILE")
+
+	JIRA_DETAILS.OAuth.ConsumerKey = os.Getenv("JIRA_OAUTH_CONSUMER_KEY")
+	JIRA_DETAILS.OAuth.AccessToken = os.Getenv("JIRA_OAUTH_ACCESS_TOKEN")
+	JIRA_DETAILS.OAuth.PrivateKey = os.Getenv("JIRA_OAUTH_PRIVATE_KEY")
+	if keyFile := os.Getenv("JIRA_OAUTH_PRIVATE_KEY_FILE"); keyFile != "" && JIRA_DETAILS.OAuth.PrivateKey == "" {
+		content, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			log.Println("[auth.go] Could not read private key:", err)
+		}
+		JIRA_DETAILS.OAuth.PrivateKey = string(content)
+	}
+}
+
+// Returns the names of the missing settings of the configured auth type
+func getMissingJIRAAuthSettings(details *JiraDetails) []string {
+	missing := []string{}
+	switch strings.ToLower(details.AuthType) {
+	case jiraAuthOAuth2:
+		if details.OAuth.ClientID == "" {
+			missing = append(missing, "JIRA_OAUTH_CLIENT_ID")
+		}
+		if details.OAuth.ClientSecret == "" {
+			missing = append(missing, "JIRA_OAUTH_CLIENT_SECRET")
+		}
+	case jiraAuthOAuth1:
+		if details.OAuth.ConsumerKey == "" {
+			missing = append(missing, "JIRA_OAUTH_CONSUMER_KEY")
+		}
+		if details.OAuth.PrivateKey == "" {
+			missing = append(missing, "JIRA_OAUTH_PRIVATE_KEY")
+		}
+		if details.OAuth.AccessToken == "" {
+			missing = append(missing, "JIRA_OAUTH_ACCESS_TOKEN")
+		}
+	default:
+		if details.APIToken == "" {
+			missing = append(missing, "JIRA_API_TOKEN")
+		}
+	}
+	return missing
+}
+
+// Builds the HTTP client for the configured auth type
+func newJIRAHTTPClient(details *JiraDetails) (*http.Client, error) {
+	switch strings.ToLower(details.AuthType) {
+	case "", jiraAuthBasic:
+		tp := jira.BasicAuthTransport{
+			Username: details.Username,
+			Password: details.APIToken,
+		}
+		return tp.Client(), nil
+	case jiraAuthPAT:
+		tp := bearerAuthTransport{Token: details.APIToken}
+		return tp.Client(), nil
+	case jiraAuthOAuth2:
+		if details.OAuth.ClientID == "" || details.OAuth.ClientSecret == "" {
+			return nil, errors.New("JIRA_OAUTH_CLIENT_ID and JIRA_OAUTH_CLIENT_SECRET are required for OAuth 2.0")
+		}
+		tp := oauth2Transport{Source: getOAuth2TokenSource(details.OAuth)}
+		return tp.Client(), nil
+	case jiraAuthOAuth1:
+		privateKey, err := parseRSAPrivateKey(details.OAuth.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		tp := oauth1Transport{
+			ConsumerKey: details.OAuth.ConsumerKey,
+			AccessToken: details.OAuth.AccessToken,
+			PrivateKey:  privateKey,
+		}
+		return tp.Client(), nil
+	}
+	return nil, fmt.Errorf("unknown auth type %s", details.AuthType)
+}
+
+// bearerAuthTransport sends the token as bearer token, which is how personal access tokens are passed to JIRA
+type bearerAuthTransport struct {
+	Token string
+}
+
+func (t *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Don't modify the request of the caller, see http.RoundTripper
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("Authorization", "Bearer "+t.Token)
+	return http.DefaultTransport.RoundTrip(req2)
+}
+
+func (t *bearerAuthTransport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+/**************************************
+*            OAUTH 2.0
+***************************************/
+
+// oauth2TokenSource fetches access tokens and caches them until shortly before they expire
+type oauth2TokenSource struct {
+	details      OAuthDetails
+	mutex        sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// Token sources live as long as the service, so that tokens are reused across events
+var oauth2TokenSources = map[string]*oauth2TokenSource{}
+var oauth2TokenSourcesMutex sync.Mutex
+
+func getOAuth2TokenSource(details OAuthDetails) *oauth2TokenSource {
+	oauth2TokenSourcesMutex.Lock()
+	defer oauth2TokenSourcesMutex.Unlock()
+
+	key := details.TokenURL + "|" + details.ClientID
+	source, ok := oauth2TokenSources[key]
+	if !ok {
+		source = &oauth2TokenSource{details: details, refreshToken: details.RefreshToken}
+		// A refresh token from an earlier refresh wins over the configured one, which might be rotated already
+		if details.TokenFile != "" {
+			if content, err := ioutil.ReadFile(details.TokenFile); err == nil && len(bytes.TrimSpace(content)) > 0 {
+				source.refreshToken = string(bytes.TrimSpace(content))
+			}
+		}
+		oauth2TokenSources[key] = source
+	}
+	return source
+}
+
+// Returns the cached access token or fetches a new one
+func (s *oauth2TokenSource) Token() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.accessToken != "" && time.Now().Add(oauthTokenExpiryDelta).Before(s.expiry) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("client_id", s.details.ClientID)
+	form.Set("client_secret", s.details.ClientSecret)
+	if s.refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", s.refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if s.details.Scope != "" {
+		form.Set("scope", s.details.Scope)
+	}
+
+	resp, err := oauthHTTPClient.PostForm(s.details.TokenURL, form)
+	if err != nil {
+		return "", &TrackerRequestError{Tracker: "JIRA", Operation: "fetch OAuth 2.0 token", Err: err}
+	}
+	defer resp.Body.Close()
+
+	tokenResponse := &oauth2TokenResponse{}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, tokenResponse); err != nil || resp.StatusCode >= 300 || tokenResponse.AccessToken == "" {
+		if tokenResponse.Error != "" {
+			err = fmt.Errorf("%s: %s", tokenResponse.Error, tokenResponse.Description)
+		} else if err == nil {
+			err = fmt.Errorf("no access token in response: %s", strings.TrimSpace(string(body)))
+		}
+		return "", &TrackerRequestError{Tracker: "JIRA", Operation: "fetch OAuth 2.0 token", StatusCode: resp.StatusCode, Err: err}
+	}
+
+	s.accessToken = tokenResponse.AccessToken
+	s.expiry = time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	if tokenResponse.RefreshToken != "" && tokenResponse.RefreshToken != s.refreshToken {
+		s.refreshToken = tokenResponse.RefreshToken
+		s.storeRefreshToken()
+	}
+
+	log.Println("[auth.go] Fetched OAuth 2.0 access token, valid until " + s.expiry.Format(time.RFC3339))
+	return s.accessToken, nil
+}
+
+// Forgets the access token, e.g. because JIRA rejected it before it expired
+func (s *oauth2TokenSource) Invalidate() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accessToken = ""
+}
+
+func (s *oauth2TokenSource) storeRefreshToken() {
+	if s.details.TokenFile == "" {
+		log.Println("[auth.go] Refresh token was rotated, set JIRA_OAUTH_TOKEN_FILE to keep it across restarts")
+		return
+	}
+	if err := ioutil.WriteFile(s.details.TokenFile, []byte(s.refreshToken), 0600); err != nil {
+		log.Println("[auth.go] Could not store refresh token:", err)
+	}
+}
+
+// oauth2Transport adds the access token to every request and retries once with a new token if JIRA answers 401
+type oauth2Transport struct {
+	Source *oauth2TokenSource
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.roundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	resp.Body.Close()
+	t.Source.Invalidate()
+
+	req2 := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if req2.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.roundTrip(req2)
+}
+
+func (t *oauth2Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't modify the request of the caller, see http.RoundTripper
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultTransport.RoundTrip(req2)
+}
+
+func (t *oauth2Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+/**************************************
+*            OAUTH 1.0a
+***************************************/
+
+// oauth1Transport signs every request with RSA-SHA1, as expected by JIRA application links
+// The access token has to be obtained once through the OAuth dance, see README
+type oauth1Transport struct {
+	ConsumerKey string
+	AccessToken string
+	PrivateKey  *rsa.PrivateKey
+}
+
+func (t *oauth1Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"oauth_consumer_key":     t.ConsumerKey,
+		"oauth_nonce":            hex.EncodeToString(nonce),
+		"oauth_signature_method": "RSA-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_token":            t.AccessToken,
+		"oauth_version":          "1.0",
+	}
+
+	signature, err := t.sign(req, params)
+	if err != nil {
+		return nil, err
+	}
+	params["oauth_signature"] = signature
+
+	header := []string{}
+	for key, value := range params {
+		header = append(header, key+"=\""+oauthEscape(value)+"\"")
+	}
+	sort.Strings(header)
+
+	// Don't modify the request of the caller, see http.RoundTripper
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("Authorization", "OAuth "+strings.Join(header, ", "))
+	return http.DefaultTransport.RoundTrip(req2)
+}
+
+// Signs the base string of the request, see https://oauth.net/core/1.0a/#signing_process
+// JIRA only sends JSON bodies, so only the query parameters are part of the signature
+func (t *oauth1Transport) sign(req *http.Request, oauthParams map[string]string) (string, error) {
+	hashed := sha1.Sum([]byte(oauthBaseString(req, oauthParams)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.PrivateKey, crypto.SHA1, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func (t *oauth1Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Builds the signature base string, see https://tools.ietf.org/html/rfc5849#section-3.4.1
+func oauthBaseString(req *http.Request, oauthParams map[string]string) string {
+	params := [][2]string{}
+	for key, value := range oauthParams {
+		params = append(params, [2]string{oauthEscape(key), oauthEscape(value)})
+	}
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			params = append(params, [2]string{oauthEscape(key), oauthEscape(value)})
+		}
+	}
+	// Sorted by name and then by value, sorting "name=value" would put "a2" in front of "a"
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+	pairs := []string{}
+	for _, param := range params {
+		pairs = append(pairs, param[0]+"="+param[1])
+	}
+
+	scheme := strings.ToLower(req.URL.Scheme)
+	host := strings.ToLower(req.URL.Hostname())
+	if port := req.URL.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	baseURL := scheme + "://" + host + req.URL.EscapedPath()
+	return strings.ToUpper(req.Method) + "&" + oauthEscape(baseURL) + "&" + oauthEscape(strings.Join(pairs, "&"))
+}
+
+// Percent encoding of RFC 3986, which differs from url.QueryEscape for spaces
+func oauthEscape(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '.' || c == '_' || c == '~' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// Accepts PKCS #1 and PKCS #8 encoded keys
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("JIRA_OAUTH_PRIVATE_KEY is not a PEM encoded key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("JIRA_OAUTH_PRIVATE_KEY is not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOAuthEscape(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "unreserved characters are kept",
+			value: "AZaz09-._~",
+			want:  "AZaz09-._~",
+		},
+		{
+			name:  "space is encoded as %20 and not as +",
+			value: "r b",
+			want:  "r%20b",
+		},
+		{
+			name:  "plus and reserved characters",
+			value: "2+q=!*'()@/?&",
+			want:  "2%2Bq%3D%21%2A%27%28%29%40%2F%3F%26",
+		},
+		{
+			name:  "percent is encoded again",
+			value: "=%3D",
+			want:  "%3D%253D",
+		},
+		{
+			name:  "UTF-8 bytes are encoded with upper case hex digits",
+			value: "é",
+			want:  "%C3%A9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oauthEscape(tt.value); got != tt.want {
+				t.Errorf("oauthEscape(%q)\n got: %q\nwant: %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthBaseString(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		params map[string]string
+		want   string
+	}{
+		{
+			// RFC 5849 section 3.4.1.1, without the form parameters of the body which JIRA never sends
+			name:   "RFC 5849 example",
+			method: "POST",
+			url:    "http://example.com/request?b5=%3D%253D&a3=a&c%40=&a2=r%20b",
+			params: map[string]string{
+				"oauth_consumer_key":     "9djdj82h48djs9d2",
+				"oauth_token":            "kkk9d7dh3k39sjv7",
+				"oauth_signature_method": "HMAC-SHA1",
+				"oauth_timestamp":        "137131201",
+				"oauth_nonce":            "7d8f3e4a",
+			},
+			want: "POST&http%3A%2F%2Fexample.com%2Frequest&a2%3Dr%2520b%26a3%3Da%26b5%3D%253D%25253D%26c%2540%3D" +
+				"%26oauth_consumer_key%3D9djdj82h48djs9d2%26oauth_nonce%3D7d8f3e4a%26oauth_signature_method%3DHMAC-SHA1" +
+				"%26oauth_timestamp%3D137131201%26oauth_token%3Dkkk9d7dh3k39sjv7",
+		},
+		{
+			// RFC 5849 section 3.4.1.2
+			name:   "default port is dropped, scheme and host are lower case",
+			method: "get",
+			url:    "HTTP://EXAMPLE.COM:80/r%20v/X?id=123",
+			want:   "GET&http%3A%2F%2Fexample.com%2Fr%2520v%2FX&id%3D123",
+		},
+		{
+			// RFC 5849 section 3.4.1.2
+			name:   "other ports are kept",
+			method: "GET",
+			url:    "https://www.example.net:8080/?q=1",
+			want:   "GET&https%3A%2F%2Fwww.example.net%3A8080%2F&q%3D1",
+		},
+		{
+			// RFC 5849 section 3.4.1.3.2
+			name:   "parameters are sorted by name and then by value",
+			method: "GET",
+			url:    "https://jira.example.com/rest/api/2/search?a2=x&a=b&a=a",
+			want:   "GET&https%3A%2F%2Fjira.example.com%2Frest%2Fapi%2F2%2Fsearch&a%3Da%26a%3Db%26a2%3Dx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if got := oauthBaseString(req, tt.params); got != tt.want {
+				t.Errorf("oauthBaseString(%s %s)\n got: %s\nwant: %s", tt.method, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuth1TransportSign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %s", err)
+	}
+	transport := &oauth1Transport{ConsumerKey: "9djdj82h48djs9d2", AccessToken: "kkk9d7dh3k39sjv7", PrivateKey: key}
+
+	req := httptest.NewRequest("GET", "https://jira.example.com/rest/api/2/search?jql=project%20%3D%20KEP", nil)
+	params := map[string]string{
+		"oauth_consumer_key":     transport.ConsumerKey,
+		"oauth_nonce":            "7d8f3e4a",
+		"oauth_signature_method": "RSA-SHA1",
+		"oauth_timestamp":        "137131201",
+		"oauth_token":            transport.AccessToken,
+		"oauth_version":          "1.0",
+	}
+
+	signature, err := transport.sign(req, params)
+	if err != nil {
+		t.Fatalf("could not sign request: %s", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		t.Fatalf("signature is not base64 encoded: %s", err)
+	}
+
+	hashed := sha1.Sum([]byte(oauthBaseString(req, params)))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hashed[:], decoded); err != nil {
+		t.Errorf("signature does not match the base string: %s", err)
+	}
+}
+
+// tokenServer is an OAuth 2.0 token endpoint which hands out numbered access tokens
+type tokenServer struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	requests  int
+	grants    []string
+	expiresIn int
+	rotate    bool
+}
+
+func newTokenServer(t *testing.T, expiresIn int, rotate bool) *tokenServer {
+	s := &tokenServer{expiresIn: expiresIn, rotate: rotate}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("could not parse token request: %s", err)
+		}
+		if r.PostForm.Get("client_id") != "client" || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"Bad client credentials"}`)
+			return
+		}
+
+		s.mutex.Lock()
+		s.requests++
+		requests := s.requests
+		s.grants = append(s.grants, r.PostForm.Get("grant_type")+" "+r.PostForm.Get("refresh_token"))
+		s.mutex.Unlock()
+
+		refreshToken := ""
+		if s.rotate {
+			refreshToken = fmt.Sprintf("refresh-%d", requests)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","refresh_token":"%s","expires_in":%d}`, requests, refreshToken, s.expiresIn)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestOAuth2TokenSourceCachesToken(t *testing.T) {
+	server := newTokenServer(t, 3600, false)
+	source := &oauth2TokenSource{details: OAuthDetails{TokenURL: server.URL, ClientID: "client", ClientSecret: "secret"}}
+
+	for i := 0; i < 3; i++ {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("Token() failed: %s", err)
+		}
+		if token != "token-1" {
+			t.Errorf("Token() = %q, want %q", token, "token-1")
+		}
+	}
+	if server.requests != 1 {
+		t.Errorf("token endpoint was called %d times, want 1", server.requests)
+	}
+	if server.grants[0] != "client_credentials " {
+		t.Errorf("grant = %q, want the client credentials grant", server.grants[0])
+	}
+}
+
+func TestOAuth2TokenSourceRefreshesToken(t *testing.T) {
+	// Tokens which expire within oauthTokenExpiryDelta are fetched again on every call
+	server := newTokenServer(t, 30, true)
+	tokenFile := filepath.Join(t.TempDir(), "refresh-token")
+	source := &oauth2TokenSource{
+		details:      OAuthDetails{TokenURL: server.URL, ClientID: "client", ClientSecret: "secret", TokenFile: tokenFile},
+		refreshToken: "refresh-0",
+	}
+
+	for i := 1; i <= 2; i++ {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("Token() failed: %s", err)
+		}
+		if want := fmt.Sprintf("token-%d", i); token != want {
+			t.Errorf("Token() = %q, want %q", token, want)
+		}
+	}
+
+	// Every refresh uses the refresh token rotated by the one before
+	wantGrants := []string{"refresh_token refresh-0", "refresh_token refresh-1"}
+	if fmt.Sprint(server.grants) != fmt.Sprint(wantGrants) {
+		t.Errorf("grants = %q, want %q", server.grants, wantGrants)
+	}
+	content, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		t.Fatalf("refresh token was not stored: %s", err)
+	}
+	if string(content) != "refresh-2" {
+		t.Errorf("stored refresh token = %q, want %q", content, "refresh-2")
+	}
+}
+
+func TestOAuth2TokenSourceReportsErrors(t *testing.T) {
+	server := newTokenServer(t, 3600, false)
+	source := &oauth2TokenSource{details: OAuthDetails{TokenURL: server.URL, ClientID: "client", ClientSecret: "wrong"}}
+
+	_, err := source.Token()
+	requestErr, ok := err.(*TrackerRequestError)
+	if !ok {
+		t.Fatalf("Token() error = %v, want a TrackerRequestError", err)
+	}
+	if requestErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status code = %d, want %d", requestErr.StatusCode, http.StatusUnauthorized)
+	}
+	if requestErr.Err.Error() != "invalid_client: Bad client credentials" {
+		t.Errorf("error = %q, want the error of the token endpoint", requestErr.Err)
+	}
+}
+
+func TestOAuth2TokenSourceTimesOut(t *testing.T) {
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stalled
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	httpClient := oauthHTTPClient
+	defer func() { oauthHTTPClient = httpClient }()
+	oauthHTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	source := &oauth2TokenSource{details: OAuthDetails{TokenURL: server.URL, ClientID: "client", ClientSecret: "secret"}}
+	if _, err := source.Token(); err == nil {
+		t.Errorf("Token() of a stalled token endpoint succeeded, want a timeout")
+	}
+}
+
+func TestOAuth2TransportRetriesWithNewToken(t *testing.T) {
+	tokenServer := newTokenServer(t, 3600, false)
+	source := &oauth2TokenSource{details: OAuthDetails{TokenURL: tokenServer.URL, ClientID: "client", ClientSecret: "secret"}}
+
+	// JIRA revokes the first token before it expires
+	authorizations := []string{}
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(body)
+	}))
+	defer jiraServer.Close()
+
+	client := (&oauth2Transport{Source: source}).Client()
+	resp, err := client.Post(jiraServer.URL, "application/json", strings.NewReader(`{"fields":{}}`))
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if string(body) != `{"fields":{}}` {
+		t.Errorf("body of the retried request = %q, want the original body", body)
+	}
+	wantAuthorizations := []string{"Bearer token-1", "Bearer token-2"}
+	if fmt.Sprint(authorizations) != fmt.Sprint(wantAuthorizations) {
+		t.Errorf("authorizations = %q, want %q", authorizations, wantAuthorizations)
+	}
+	if tokenServer.requests != 2 {
+		t.Errorf("token endpoint was called %d times, want 2", tokenServer.requests)
+	}
+}
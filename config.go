@@ -0,0 +1,245 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"gopkg.in/yaml.v3"
+)
+
+// JiraConfigResource is the name of the resource in the Keptn configuration repo
+// which overrides the JIRA settings of the jira-service for a project, stage or service
+const JiraConfigResource = "jira.yaml"
+
+// JiraConfig describes the content of a jira.yaml resource
+// Every field that is set overrides the value that comes from the environment
+type JiraConfig struct {
+	// Tracker is jira or github
+	Tracker string `yaml:"tracker"`
+	// GitHub overrides the repository and assignee for GitHub Issues
+	GitHub struct {
+		Repository string `yaml:"repository"`
+		Assignee   string `yaml:"assignee"`
+	} `yaml:"github"`
+	// APIVersion is 2 or 3
+	APIVersion           string   `yaml:"apiVersion"`
+	ProjectKey           string   `yaml:"projectKey"`
+	IssueType            string   `yaml:"issueType"`
+	AssigneeID           string   `yaml:"assigneeId"`
+	ReporterID           string   `yaml:"reporterId"`
+	Labels               []string `yaml:"labels"`
+	TicketForProblems    *bool    `yaml:"ticketForProblems"`
+	TicketForEvaluations *bool    `yaml:"ticketForEvaluations"`
+	TicketForApprovals   *bool    `yaml:"ticketForApprovals"`
+	TicketForTasks       []string `yaml:"ticketForTasks"`
+	TicketForAnyTask     *bool    `yaml:"ticketForAnyTask"`
+	TicketForResults     []string `yaml:"ticketForResults"`
+	CommentTimeline      *bool    `yaml:"commentTimeline"`
+	TimelineTasks        []string `yaml:"timelineTasks"`
+	ApprovedStatus       string   `yaml:"approvedStatus"`
+	RejectedStatus       string   `yaml:"rejectedStatus"`
+	// Templates are keyed by the kind of ticket: problem, evaluation, jira, approval or the name of a task
+	Templates map[string]TicketTemplate `yaml:"templates"`
+	// WebhookRules map JIRA webhook events to Keptn events
+	WebhookRules []WebhookRule `yaml:"webhookRules"`
+	// Priorities map result, score, stage and problem impact to JIRA priorities
+	Priorities []PriorityRule `yaml:"priorities"`
+	// CustomFields map values of the event to JIRA custom fields
+	CustomFields []CustomFieldMapping `yaml:"customFields"`
+	// Routes pick the JIRA project, issue type and assignee per event
+	Routes []RoutingRule `yaml:"routes"`
+	// ServiceComponent, VersionField and the create flags set components and versions from the service and artifact
+	ServiceComponent *bool `yaml:"serviceComponent"`
+	CreateComponents *bool `yaml:"createComponents"`
+	// VersionField is fixVersions or affectsVersions
+	VersionField   string `yaml:"versionField"`
+	VersionLabel   string `yaml:"versionLabel"`
+	CreateVersions *bool  `yaml:"createVersions"`
+	// AttachPayloads uploads the CloudEvent, the evaluation and the SLO file to the ticket
+	AttachPayloads *bool `yaml:"attachPayloads"`
+}
+
+// Loads jira.yaml for the project, stage and service of the incoming event and applies it on a copy of JIRA_DETAILS
+// The more specific resource wins: service overrides stage, stage overrides project
+// JIRA_DETAILS itself is never changed after startup, as events are processed concurrently
+func loadJIRADetails(myKeptn *keptnv2.Keptn) *JiraDetails {
+	details := JIRA_DETAILS
+
+	// apply writes into the templates, all other fields are replaced as a whole
+	details.Templates = map[string]TicketTemplate{}
+	for kind, ticketTemplate := range JIRA_DETAILS.Templates {
+		details.Templates[kind] = ticketTemplate
+	}
+
+	for _, config := range loadJIRAConfigs(myKeptn) {
+		config.apply(&details)
+	}
+	return &details
+}
+
+func loadJIRAConfigs(myKeptn *keptnv2.Keptn) []*JiraConfig {
+	configs := []*JiraConfig{}
+
+	// When running locally, jira.yaml is read from the working directory
+	if keptnOptions.UseLocalFileSystem {
+		content, err := ioutil.ReadFile(JiraConfigResource)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Println("[config.go] Could not read local "+JiraConfigResource+":", err)
+			}
+			return configs
+		}
+		if config := parseJIRAConfig(content, "local"); config != nil {
+			configs = append(configs, config)
+		}
+		return configs
+	}
+
+	project := myKeptn.Event.GetProject()
+	stage := myKeptn.Event.GetStage()
+	service := myKeptn.Event.GetService()
+
+	if project == "" {
+		return configs
+	}
+
+	if resource, err := myKeptn.ResourceHandler.GetProjectResource(project, JiraConfigResource); err == nil {
+		if config := parseJIRAConfig([]byte(resource.ResourceContent), "project"); config != nil {
+			configs = append(configs, config)
+		}
+	}
+
+	if stage == "" {
+		return configs
+	}
+
+	if resource, err := myKeptn.ResourceHandler.GetStageResource(project, stage, JiraConfigResource); err == nil {
+		if config := parseJIRAConfig([]byte(resource.ResourceContent), "stage"); config != nil {
+			configs = append(configs, config)
+		}
+	}
+
+	if service == "" {
+		return configs
+	}
+
+	if resource, err := myKeptn.ResourceHandler.GetServiceResource(project, stage, service, JiraConfigResource); err == nil {
+		if config := parseJIRAConfig([]byte(resource.ResourceContent), "service"); config != nil {
+			configs = append(configs, config)
+		}
+	}
+
+	return configs
+}
+
+func parseJIRAConfig(content []byte, level string) *JiraConfig {
+	config := &JiraConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		log.Println("[config.go] Could not parse "+JiraConfigResource+" on "+level+" level:", err)
+		return nil
+	}
+	log.Println("[config.go] Using " + JiraConfigResource + " from " + level + " level")
+	return config
+}
+
+func (c *JiraConfig) apply(details *JiraDetails) {
+	if c.Tracker != "" {
+		details.Tracker = c.Tracker
+	}
+	if c.GitHub.Repository != "" {
+		details.GitHub.Repository = c.GitHub.Repository
+	}
+	if c.GitHub.Assignee != "" {
+		details.GitHub.Assignee = c.GitHub.Assignee
+	}
+	if c.APIVersion != "" {
+		details.APIVersion = c.APIVersion
+	}
+	if c.ProjectKey != "" {
+		details.ProjectKey = c.ProjectKey
+	}
+	if c.IssueType != "" {
+		details.IssueType = c.IssueType
+	}
+	if c.AssigneeID != "" {
+		details.AssigneeID = c.AssigneeID
+	}
+	if c.ReporterID != "" {
+		details.ReporterID = c.ReporterID
+	}
+	if c.Labels != nil {
+		details.Labels = c.Labels
+	}
+	if c.TicketForProblems != nil {
+		details.TicketForProblems = *c.TicketForProblems
+	}
+	if c.TicketForEvaluations != nil {
+		details.TicketForEvaluations = *c.TicketForEvaluations
+	}
+	if c.TicketForApprovals != nil {
+		details.TicketForApprovals = *c.TicketForApprovals
+	}
+	if c.TicketForTasks != nil {
+		details.TicketForTasks = c.TicketForTasks
+	}
+	if c.TicketForAnyTask != nil {
+		details.TicketForAnyTask = *c.TicketForAnyTask
+	}
+	if c.TicketForResults != nil {
+		details.TicketForResults = c.TicketForResults
+	}
+	if c.CommentTimeline != nil {
+		details.CommentTimeline = *c.CommentTimeline
+	}
+	if c.TimelineTasks != nil {
+		details.TimelineTasks = c.TimelineTasks
+	}
+	if c.ApprovedStatus != "" {
+		details.ApprovedStatus = c.ApprovedStatus
+	}
+	if c.RejectedStatus != "" {
+		details.RejectedStatus = c.RejectedStatus
+	}
+	if c.WebhookRules != nil {
+		details.WebhookRules = c.WebhookRules
+	}
+	if c.Priorities != nil {
+		details.Priorities = c.Priorities
+	}
+	if c.CustomFields != nil {
+		details.CustomFields = c.CustomFields
+	}
+	if c.Routes != nil {
+		details.Routes = c.Routes
+	}
+	if c.ServiceComponent != nil {
+		details.ServiceComponent = *c.ServiceComponent
+	}
+	if c.CreateComponents != nil {
+		details.CreateComponents = *c.CreateComponents
+	}
+	if c.VersionField != "" {
+		details.VersionField = c.VersionField
+	}
+	if c.VersionLabel != "" {
+		details.VersionLabel = c.VersionLabel
+	}
+	if c.CreateVersions != nil {
+		details.CreateVersions = *c.CreateVersions
+	}
+	if c.AttachPayloads != nil {
+		details.AttachPayloads = *c.AttachPayloads
+	}
+	for kind, ticketTemplate := range c.Templates {
+		existing := details.Templates[kind]
+		if ticketTemplate.Summary != "" {
+			existing.Summary = ticketTemplate.Summary
+		}
+		if ticketTemplate.Description != "" {
+			existing.Description = ticketTemplate.Description
+		}
+		details.Templates[kind] = existing
+	}
+}
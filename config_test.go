@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/keptn/go-utils/pkg/api/utils"
+	keptn "github.com/keptn/go-utils/pkg/lib/keptn"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// newConfigurationService serves the given resources like the Keptn configuration service, keyed by their path
+func newConfigurationService(t *testing.T, resources map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := resources[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"resourceURI":     JiraConfigResource,
+			"resourceContent": base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newConfigTestKeptn(server *httptest.Server, project string, stage string, service string) *keptnv2.Keptn {
+	return &keptnv2.Keptn{KeptnBase: keptn.KeptnBase{
+		Event:           &keptnv2.EventData{Project: project, Stage: stage, Service: service},
+		ResourceHandler: api.NewResourceHandler(server.URL),
+	}}
+}
+
+func TestLoadJIRADetails(t *testing.T) {
+	server := newConfigurationService(t, map[string]string{
+		"/v1/project/sockshop/resource/jira.yaml": `
+projectKey: SHOP
+issueType: Task
+labels: [keptn]
+templates:
+  evaluation:
+    summary: "project summary"
+    description: "project description"
+`,
+		"/v1/project/sockshop/stage/production/resource/jira.yaml": `
+issueType: Bug
+ticketForEvaluations: false
+`,
+		"/v1/project/sockshop/stage/production/service/carts/resource/jira.yaml": `
+labels: [carts]
+templates:
+  evaluation:
+    summary: "service summary"
+`,
+	})
+
+	useLocalFileSystem := keptnOptions.UseLocalFileSystem
+	defaultDetails := JIRA_DETAILS
+	defer func() {
+		keptnOptions.UseLocalFileSystem = useLocalFileSystem
+		JIRA_DETAILS = defaultDetails
+	}()
+	keptnOptions.UseLocalFileSystem = false
+	JIRA_DETAILS = JiraDetails{
+		ProjectKey:           "KEP",
+		IssueType:            "Problem",
+		AssigneeID:           "default-assignee",
+		TicketForEvaluations: true,
+		Templates:            map[string]TicketTemplate{"problem": {Summary: "default problem summary"}},
+	}
+
+	tests := []struct {
+		name                 string
+		project              string
+		stage                string
+		service              string
+		projectKey           string
+		issueType            string
+		labels               []string
+		ticketForEvaluations bool
+		templates            map[string]TicketTemplate
+	}{
+		{
+			name:                 "service overrides stage, stage overrides project",
+			project:              "sockshop",
+			stage:                "production",
+			service:              "carts",
+			projectKey:           "SHOP",
+			issueType:            "Bug",
+			labels:               []string{"carts"},
+			ticketForEvaluations: false,
+			templates: map[string]TicketTemplate{
+				"problem":    {Summary: "default problem summary"},
+				"evaluation": {Summary: "service summary", Description: "project description"},
+			},
+		},
+		{
+			name:                 "missing service resource keeps project and stage",
+			project:              "sockshop",
+			stage:                "production",
+			service:              "orders",
+			projectKey:           "SHOP",
+			issueType:            "Bug",
+			labels:               []string{"keptn"},
+			ticketForEvaluations: false,
+			templates: map[string]TicketTemplate{
+				"problem":    {Summary: "default problem summary"},
+				"evaluation": {Summary: "project summary", Description: "project description"},
+			},
+		},
+		{
+			name:                 "event without stage only reads the project resource",
+			project:              "sockshop",
+			projectKey:           "SHOP",
+			issueType:            "Task",
+			labels:               []string{"keptn"},
+			ticketForEvaluations: true,
+			templates: map[string]TicketTemplate{
+				"problem":    {Summary: "default problem summary"},
+				"evaluation": {Summary: "project summary", Description: "project description"},
+			},
+		},
+		{
+			name:                 "project without jira.yaml keeps the settings from the environment",
+			project:              "podtato-head",
+			stage:                "production",
+			service:              "carts",
+			projectKey:           "KEP",
+			issueType:            "Problem",
+			ticketForEvaluations: true,
+			templates:            map[string]TicketTemplate{"problem": {Summary: "default problem summary"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := loadJIRADetails(newConfigTestKeptn(server, tt.project, tt.stage, tt.service))
+
+			if details.ProjectKey != tt.projectKey {
+				t.Errorf("ProjectKey = %q, want %q", details.ProjectKey, tt.projectKey)
+			}
+			if details.IssueType != tt.issueType {
+				t.Errorf("IssueType = %q, want %q", details.IssueType, tt.issueType)
+			}
+			if details.AssigneeID != "default-assignee" {
+				t.Errorf("AssigneeID = %q, want the one from the environment", details.AssigneeID)
+			}
+			if !stringSlicesEqual(details.Labels, tt.labels) {
+				t.Errorf("Labels = %q, want %q", details.Labels, tt.labels)
+			}
+			if details.TicketForEvaluations != tt.ticketForEvaluations {
+				t.Errorf("TicketForEvaluations = %t, want %t", details.TicketForEvaluations, tt.ticketForEvaluations)
+			}
+			if len(details.Templates) != len(tt.templates) {
+				t.Errorf("Templates = %v, want %v", details.Templates, tt.templates)
+			}
+			for kind, want := range tt.templates {
+				if got := details.Templates[kind]; got != want {
+					t.Errorf("Templates[%s] = %+v, want %+v", kind, got, want)
+				}
+			}
+		})
+	}
+
+	// Events are processed concurrently, so the settings from the environment must stay as they are
+	if JIRA_DETAILS.ProjectKey != "KEP" || len(JIRA_DETAILS.Templates) != 1 {
+		t.Errorf("JIRA_DETAILS was changed: %+v", JIRA_DETAILS)
+	}
+}
+
+func stringSlicesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
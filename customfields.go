@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types custom field values are converted to, see CustomFieldMapping
+const (
+	customFieldText        = "text"
+	customFieldNumber      = "number"
+	customFieldSelect      = "select"
+	customFieldMultiSelect = "multiselect"
+	customFieldLabels      = "labels"
+	customFieldDate        = "date"
+	customFieldDateTime    = "datetime"
+	customFieldUser        = "user"
+)
+
+// CustomFieldMapping fills a JIRA custom field with a value from the event
+// Mappings are configured in jira.yaml
+type CustomFieldMapping struct {
+	// Field is the ID of the custom field, e.g. customfield_10042
+	Field string `yaml:"field"`
+	// Source is the path of the value, e.g. keptnContext, data.evaluation.score or labels.buildId
+	Source string `yaml:"source"`
+	// Type is text (default), number, select, multiselect, labels, date, datetime or user
+	Type string `yaml:"type"`
+}
+
+// Resolves the sources of the configured custom fields and converts the values into what JIRA expects for the type of field
+// Fields whose source doesn't exist or can't be converted are left out
+func getJIRACustomFields(templateData *TicketTemplateData, details *JiraDetails) map[string]interface{} {
+	if len(details.CustomFields) == 0 {
+		return nil
+	}
+
+	root := newCustomFieldSourceRoot(templateData)
+	fields := map[string]interface{}{}
+	for _, mapping := range details.CustomFields {
+		value, ok := resolveCustomFieldSource(root, mapping.Source)
+		if !ok || value == nil {
+			log.Println("[customfields.go] Skipping custom field " + mapping.Field + ": " + mapping.Source + " is not set")
+			continue
+		}
+
+		converted, err := convertCustomFieldValue(value, mapping.Type, details)
+		if err != nil {
+			log.Println("[customfields.go] Skipping custom field "+mapping.Field+":", err)
+			continue
+		}
+		fields[mapping.Field] = converted
+	}
+	return fields
+}
+
+// The root of the source paths: the raw event data is available as data, its labels also as labels
+func newCustomFieldSourceRoot(templateData *TicketTemplateData) map[string]interface{} {
+	root := map[string]interface{}{
+		"keptnContext": templateData.KeptnContext,
+		"eventType":    templateData.EventType,
+		"bridgeURL":    templateData.BridgeURL,
+		"project":      templateData.Project,
+		"stage":        templateData.Stage,
+		"service":      templateData.Service,
+		"data":         templateData.Event,
+		"labels":       map[string]interface{}{},
+	}
+	if labels, ok := templateData.Event["labels"]; ok && labels != nil {
+		root["labels"] = labels
+	}
+	return root
+}
+
+// Walks the dot separated path through maps and arrays, e.g. data.evaluation.indicatorResults.0.score
+func resolveCustomFieldSource(root map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = root
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func convertCustomFieldValue(value interface{}, fieldType string, details *JiraDetails) (interface{}, error) {
+	switch strings.ToLower(fieldType) {
+	case "", customFieldText:
+		return stringifyCustomFieldValue(value), nil
+	case customFieldNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+		return nil, fmt.Errorf("%v is not a number", value)
+	case customFieldSelect:
+		return map[string]string{"value": stringifyCustomFieldValue(value)}, nil
+	case customFieldMultiSelect:
+		options := []map[string]string{}
+		for _, v := range splitCustomFieldValue(value) {
+			options = append(options, map[string]string{"value": v})
+		}
+		return options, nil
+	case customFieldLabels:
+		// JIRA labels can't contain spaces
+		labels := []string{}
+		for _, v := range splitCustomFieldValue(value) {
+			labels = append(labels, strings.ReplaceAll(v, " ", "_"))
+		}
+		return labels, nil
+	case customFieldDate:
+		t, err := parseCustomFieldTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format("2006-01-02"), nil
+	case customFieldDateTime:
+		t, err := parseCustomFieldTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format("2006-01-02T15:04:05.000-0700"), nil
+	case customFieldUser:
+		// Same as for assignee and reporter: account IDs on JIRA Cloud, usernames on JIRA Server
+		if strings.EqualFold(details.Deployment, jiraDeploymentServer) {
+			return map[string]string{"name": stringifyCustomFieldValue(value)}, nil
+		}
+		return map[string]string{"accountId": stringifyCustomFieldValue(value)}, nil
+	}
+	return nil, fmt.Errorf("unknown type %s", fieldType)
+}
+
+// Numbers are formatted without exponent, maps and arrays as JSON
+func stringifyCustomFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		content, _ := json.Marshal(v)
+		return string(content)
+	}
+	return fmt.Sprint(value)
+}
+
+// Arrays are taken as they are, strings are split by comma
+func splitCustomFieldValue(value interface{}) []string {
+	values := []string{}
+	if array, ok := value.([]interface{}); ok {
+		for _, v := range array {
+			values = append(values, stringifyCustomFieldValue(v))
+		}
+		return values
+	}
+	for _, v := range strings.Split(stringifyCustomFieldValue(value), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Keptn uses RFC 3339 timestamps, plain dates and unix timestamps are accepted too
+func parseCustomFieldTime(value interface{}) (time.Time, error) {
+	if seconds, ok := value.(float64); ok {
+		return time.Unix(int64(seconds), 0).UTC(), nil
+	}
+
+	text := stringifyCustomFieldValue(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z0700", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s is not a date", text)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertCustomFieldValue(t *testing.T) {
+	// Values are typed as they come out of the JSON of the event: strings, float64, maps and arrays
+	tests := []struct {
+		name       string
+		value      interface{}
+		fieldType  string
+		deployment string
+		want       interface{}
+		wantErr    bool
+	}{
+		{
+			name:  "text from a string",
+			value: "carts",
+			want:  "carts",
+		},
+		{
+			name:      "text from a fraction",
+			value:     0.95,
+			fieldType: "text",
+			want:      "0.95",
+		},
+		{
+			name:  "text from a large number has no exponent",
+			value: 1e21,
+			want:  "1000000000000000000000",
+		},
+		{
+			name:  "text from an object is JSON",
+			value: map[string]interface{}{"score": 90.0},
+			want:  `{"score":90}`,
+		},
+		{
+			name:      "number from a number",
+			value:     90.5,
+			fieldType: "number",
+			want:      90.5,
+		},
+		{
+			name:      "number from a string",
+			value:     " 42 ",
+			fieldType: "number",
+			want:      42.0,
+		},
+		{
+			name:      "type is case insensitive",
+			value:     "7",
+			fieldType: "Number",
+			want:      7.0,
+		},
+		{
+			name:      "number from a string which is no number",
+			value:     "fast",
+			fieldType: "number",
+			wantErr:   true,
+		},
+		{
+			name:      "number from a boolean",
+			value:     true,
+			fieldType: "number",
+			wantErr:   true,
+		},
+		{
+			name:      "select option from a string",
+			value:     "gold",
+			fieldType: "select",
+			want:      map[string]string{"value": "gold"},
+		},
+		{
+			name:      "select option from a number",
+			value:     3.0,
+			fieldType: "select",
+			want:      map[string]string{"value": "3"},
+		},
+		{
+			name:      "multiselect options from a comma separated string",
+			value:     "gold, silver,,bronze ",
+			fieldType: "multiselect",
+			want:      []map[string]string{{"value": "gold"}, {"value": "silver"}, {"value": "bronze"}},
+		},
+		{
+			name:      "multiselect options from an array",
+			value:     []interface{}{"gold", 2.0},
+			fieldType: "multiselect",
+			want:      []map[string]string{{"value": "gold"}, {"value": "2"}},
+		},
+		{
+			name:      "multiselect from an empty string has no options",
+			value:     "",
+			fieldType: "multiselect",
+			want:      []map[string]string{},
+		},
+		{
+			name:      "labels without spaces",
+			value:     "team carts, keptn",
+			fieldType: "labels",
+			want:      []string{"team_carts", "keptn"},
+		},
+		{
+			name:      "date from an RFC 3339 time",
+			value:     "2021-06-07T10:30:00.000Z",
+			fieldType: "date",
+			want:      "2021-06-07",
+		},
+		{
+			name:      "date from a unix timestamp",
+			value:     1623061800.0,
+			fieldType: "date",
+			want:      "2021-06-07",
+		},
+		{
+			name:      "date from a JIRA time",
+			value:     "2021-06-07T10:30:00.000+0000",
+			fieldType: "date",
+			want:      "2021-06-07",
+		},
+		{
+			name:      "datetime from a plain date",
+			value:     "2021-06-07",
+			fieldType: "datetime",
+			want:      "2021-06-07T00:00:00.000+0000",
+		},
+		{
+			name:      "datetime keeps the offset",
+			value:     "2021-06-07T12:30:00+02:00",
+			fieldType: "datetime",
+			want:      "2021-06-07T12:30:00.000+0200",
+		},
+		{
+			name:      "date which can't be parsed",
+			value:     "yesterday",
+			fieldType: "date",
+			wantErr:   true,
+		},
+		{
+			name:      "user on JIRA Cloud",
+			value:     "5b10a2844c20165700ede21g",
+			fieldType: "user",
+			want:      map[string]string{"accountId": "5b10a2844c20165700ede21g"},
+		},
+		{
+			name:       "user on JIRA Server",
+			value:      "jsmith",
+			fieldType:  "user",
+			deployment: "server",
+			want:       map[string]string{"name": "jsmith"},
+		},
+		{
+			name:      "unknown type",
+			value:     "carts",
+			fieldType: "cascading",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertCustomFieldValue(tt.value, tt.fieldType, &JiraDetails{Deployment: tt.deployment})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("convertCustomFieldValue(%v, %q) = %v, want an error", tt.value, tt.fieldType, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertCustomFieldValue(%v, %q) failed: %s", tt.value, tt.fieldType, err)
+			}
+
+			// Compare the JSON which is sent to JIRA
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("convertCustomFieldValue(%v, %q)\n got: %s\nwant: %s", tt.value, tt.fieldType, gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestGetJIRACustomFields(t *testing.T) {
+	event := map[string]interface{}{}
+	content := `{
+		"project": "sockshop",
+		"result": "fail",
+		"labels": {"buildId": "1234", "owner": "team carts"},
+		"evaluation": {
+			"score": 42.5,
+			"indicatorResults": [{"value": {"metric": "response_time_p95", "value": 612.3}}]
+		},
+		"deploymentURIsPublic": null
+	}`
+	if err := json.Unmarshal([]byte(content), &event); err != nil {
+		t.Fatalf("could not decode event: %s", err)
+	}
+	templateData := &TicketTemplateData{KeptnContext: "context-1", Project: "sockshop", Event: event}
+
+	tests := []struct {
+		name    string
+		mapping CustomFieldMapping
+		// want is nil if the field is left out
+		want interface{}
+	}{
+		{
+			name:    "field of the ticket data",
+			mapping: CustomFieldMapping{Source: "keptnContext"},
+			want:    "context-1",
+		},
+		{
+			name:    "number of the event",
+			mapping: CustomFieldMapping{Source: "data.evaluation.score", Type: "number"},
+			want:    42.5,
+		},
+		{
+			name:    "label as number",
+			mapping: CustomFieldMapping{Source: "labels.buildId", Type: "number"},
+			want:    1234.0,
+		},
+		{
+			name:    "array index in the path",
+			mapping: CustomFieldMapping{Source: "data.evaluation.indicatorResults.0.value.metric", Type: "select"},
+			want:    map[string]string{"value": "response_time_p95"},
+		},
+		{
+			name:    "missing label",
+			mapping: CustomFieldMapping{Source: "labels.version"},
+		},
+		{
+			name:    "array index out of range",
+			mapping: CustomFieldMapping{Source: "data.evaluation.indicatorResults.1.value"},
+		},
+		{
+			name:    "path through a string",
+			mapping: CustomFieldMapping{Source: "data.result.value"},
+		},
+		{
+			name:    "null value",
+			mapping: CustomFieldMapping{Source: "data.deploymentURIsPublic"},
+		},
+		{
+			name:    "value which can't be converted",
+			mapping: CustomFieldMapping{Source: "labels.owner", Type: "number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mapping.Field = "customfield_10042"
+			fields := getJIRACustomFields(templateData, &JiraDetails{CustomFields: []CustomFieldMapping{tt.mapping}})
+
+			got, ok := fields[tt.mapping.Field]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("getJIRACustomFields(%q) = %v, want the field to be left out", tt.mapping.Source, got)
+				}
+				return
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("getJIRACustomFields(%q)\n got: %s\nwant: %s", tt.mapping.Source, gotJSON, wantJSON)
+			}
+		})
+	}
+
+	if fields := getJIRACustomFields(templateData, &JiraDetails{}); fields != nil {
+		t.Errorf("getJIRACustomFields() without mappings = %v, want nil", fields)
+	}
+}
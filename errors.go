@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// PayloadError is returned if the data of an incoming CloudEvent can't be parsed
+type PayloadError struct {
+	EventType string
+	Err       error
+}
+
+func (e *PayloadError) Error() string {
+	return fmt.Sprintf("could not parse data of %s event: %s", e.EventType, e.Err)
+}
+
+func (e *PayloadError) Unwrap() error {
+	return e.Err
+}
+
+// TrackerClientError is returned if no client for the issue tracker can be created, e.g. because JIRA_BASE_URL is invalid
+type TrackerClientError struct {
+	Tracker string
+	Err     error
+}
+
+func (e *TrackerClientError) Error() string {
+	return fmt.Sprintf("could not create %s client: %s", e.Tracker, e.Err)
+}
+
+func (e *TrackerClientError) Unwrap() error {
+	return e.Err
+}
+
+// TrackerRequestError is returned if a call to the issue tracker failed
+// StatusCode is 0 if the tracker couldn't be reached at all
+type TrackerRequestError struct {
+	Tracker    string
+	Operation  string
+	StatusCode int
+	Err        error
+}
+
+func (e *TrackerRequestError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("could not %s in %s: %s", e.Operation, e.Tracker, e.Err)
+	}
+	return fmt.Sprintf("could not %s in %s (HTTP %d): %s", e.Operation, e.Tracker, e.StatusCode, e.Err)
+}
+
+func (e *TrackerRequestError) Unwrap() error {
+	return e.Err
+}
+
+// TicketQueuedError is returned if a ticket couldn't be created right away and was added to the outbox
+// The ticket is created later on, so callers must not report the failure to Keptn
+type TicketQueuedError struct {
+	Err error
+}
+
+func (e *TicketQueuedError) Error() string {
+	return fmt.Sprintf("ticket is retried through the outbox: %s", e.Err)
+}
+
+func (e *TicketQueuedError) Unwrap() error {
+	return e.Err
+}
+
+func isTicketQueuedError(err error) bool {
+	var queuedError *TicketQueuedError
+	return errors.As(err, &queuedError)
+}
+
+func newJiraRequestError(operation string, response *jira.Response, err error) *TrackerRequestError {
+	requestError := &TrackerRequestError{Tracker: "JIRA", Operation: operation, Err: err}
+	if response != nil {
+		requestError.StatusCode = response.StatusCode
+	}
+	return requestError
+}
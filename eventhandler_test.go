@@ -1 +1,25 @@
 package main
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func TestFormatEventTime(t *testing.T) {
+	withTime := cloudevents.NewEvent()
+	withTime.SetTime(time.Date(2021, 6, 7, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	if got := formatEventTime(withTime); got != "2021-06-07T08:30:00Z" {
+		t.Errorf("formatEventTime() = %q, want the time of the event in UTC", got)
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	got, err := time.Parse(time.RFC3339, formatEventTime(cloudevents.NewEvent()))
+	if err != nil {
+		t.Fatalf("formatEventTime() is no RFC 3339 time: %s", err)
+	}
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("formatEventTime() of an event without time = %s, want the current time", got)
+	}
+}
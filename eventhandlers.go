@@ -10,46 +10,165 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2" // make sure to use v2 cloudevents here
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
-	jira "gopkg.in/andygrunwald/go-jira.v1"
 )
 
-func HandleEvaluationFinishedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *keptnv2.EvaluationFinishedEventData) {
+func HandleEvaluationFinishedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *keptnv2.EvaluationFinishedEventData, details *JiraDetails) error {
 	log.Println("[eventhandlers.go] Handling evaluation.finished Event:", incomingEvent.Context.GetID())
 
-	if !JIRA_DETAILS.TicketForEvaluations {
+	if !details.TicketForEvaluations {
 		log.Println("[eventhandlers.go] TicketForEvaluations flag is set to false. Got an evaluation.finished from Keptn but doing nothing. If you want a ticket, set flag to true")
-		return
+		return nil
 	}
 
-	issueKey := createJIRATicketForEvaluationFinished(myKeptn, data)
-	ticketURL := JIRA_DETAILS.BaseURL + "/browse/" + issueKey
+	ticket, err := createJIRATicketForEvaluationFinished(myKeptn, data, details)
+	if isTicketQueuedError(err) {
+		log.Println("[eventhandlers.go] Ticket for evaluation.finished will be created later on:", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ticketURL := ticket.URL
 
 	// If the SEND_EVENT flag is set in service.yaml send an event to the relevant tool
 	SEND_EVENT, _ := strconv.ParseBool(os.Getenv("SEND_EVENT"))
 	if SEND_EVENT {
 		sendEventForEvaluationFinishedEvents("dynatrace", "CUSTOM_INFO", ticketURL, data, myKeptn)
 	}
+
+	return nil
 }
 
-func HandleProblemEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *keptnv2.ActionFinishedEventData) {
+func HandleProblemEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *ProblemEventData, details *JiraDetails) error {
 	log.Printf("[eventhandlers.go] Handling problem event: %s", incomingEvent.Context.GetID())
 
-	if !JIRA_DETAILS.TicketForProblems {
+	if !details.TicketForProblems {
 		log.Println("[eventhandlers.go] TicketForProblems flag is set to false. Got a problem from Keptn but doing nothing. If you want a ticket, set flag to true")
-		return
+		return nil
 	}
 
-	issueKey := createJIRATicketForProblem(myKeptn, data)
-	ticketURL := JIRA_DETAILS.BaseURL + "/browse/" + issueKey
+	ticket, err := createJIRATicketForProblem(myKeptn, data, details)
+	if isTicketQueuedError(err) {
+		log.Println("[eventhandlers.go] Ticket for problem will be created later on:", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ticketURL := ticket.URL
 
 	// If the SEND_EVENT flag is set in service.yaml send an event to the relevant tool
 	SEND_EVENT, _ := strconv.ParseBool(os.Getenv("SEND_EVENT"))
 	if SEND_EVENT {
 		sendEventForProblemEvents("dynatrace", "CUSTOM_INFO", ticketURL, data, myKeptn)
 	}
+
+	return nil
+}
+
+func HandleProblemClosedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *ProblemEventData, details *JiraDetails) error {
+	log.Printf("[eventhandlers.go] Handling closed problem event: %s", incomingEvent.Context.GetID())
+
+	if !details.TicketForProblems {
+		log.Println("[eventhandlers.go] TicketForProblems flag is set to false. Got a closed problem from Keptn but doing nothing.")
+		return nil
+	}
+
+	problemID := data.PID
+	if problemID == "" {
+		problemID = data.ProblemID
+	}
+	comment := "Problem " + problemID + " was closed at " + formatEventTime(incomingEvent)
+	return resolveTicketForContext(myKeptn.KeptnContext, comment, details)
+}
+
+func HandleRemediationFinishedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *keptnv2.EventData, details *JiraDetails) error {
+	log.Printf("[eventhandlers.go] Handling remediation.finished event: %s", incomingEvent.Context.GetID())
+
+	if !details.TicketForProblems {
+		log.Println("[eventhandlers.go] TicketForProblems flag is set to false. Got a remediation.finished from Keptn but doing nothing.")
+		return nil
+	}
+
+	if data.Result != keptnv2.ResultPass {
+		log.Println("[eventhandlers.go] Remediation did not succeed (result: " + string(data.Result) + "). Escalating ticket")
+		comment := "Remediation finished with result " + getResultWithIcon(string(data.Result)) + " at " + formatEventTime(incomingEvent)
+		if data.Message != "" {
+			comment += ": " + data.Message
+		}
+		return escalateTicketForContext(myKeptn.KeptnContext, comment, details)
+	}
+
+	comment := "Problem was remediated successfully at " + formatEventTime(incomingEvent)
+	return resolveTicketForContext(myKeptn.KeptnContext, comment, details)
+}
+
+// Keptn 0.8+ starts the remediation sequence instead of sending a problem event, the ticket is the same
+func HandleRemediationTriggeredEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *RemediationTriggeredEventData, details *JiraDetails) error {
+	log.Printf("[eventhandlers.go] Handling remediation.triggered event: %s", incomingEvent.Context.GetID())
+
+	return HandleProblemEvent(myKeptn, incomingEvent, &ProblemEventData{EventData: data.EventData, ProblemData: data.Problem}, details)
+}
+
+// Adds a comment for every remediation action to the ticket of the problem
+func HandleRemediationStatusChangedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *RemediationStatusChangedEventData, details *JiraDetails) error {
+	log.Printf("[eventhandlers.go] Handling remediation.status.changed event: %s", incomingEvent.Context.GetID())
+
+	if !details.TicketForProblems {
+		log.Println("[eventhandlers.go] TicketForProblems flag is set to false. Got a remediation.status.changed from Keptn but doing nothing.")
+		return nil
+	}
+
+	action := data.Remediation.Result
+	if action == "" {
+		action = data.Message
+	}
+	comment := fmt.Sprintf("Remediation action %d at %s: %s", data.Remediation.ActionIndex+1, formatEventTime(incomingEvent), action)
+	return commentTicketForContext(myKeptn.KeptnContext, comment, details)
+}
+
+// Executes the jira task: sends .started, creates the ticket and sends .finished with the issue key and URL
+func HandleJiraTriggeredEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, data *JiraTriggeredEventData, details *JiraDetails) error {
+	log.Printf("[eventhandlers.go] Handling jira.triggered event: %s", incomingEvent.Context.GetID())
+
+	_, err := myKeptn.SendTaskStartedEvent(&keptnv2.EventData{}, ServiceName)
+	if err != nil {
+		return fmt.Errorf("could not send jira.started event: %w", err)
+	}
+
+	finishedEventData := &JiraFinishedEventData{
+		EventData: keptnv2.EventData{
+			Status: keptnv2.StatusSucceeded,
+			Result: keptnv2.ResultPass,
+		},
+	}
+
+	ticket, ticketErr := createJIRATicketForJiraTask(myKeptn, data, details)
+	if isTicketQueuedError(ticketErr) {
+		// The ticket is created later on, so the task didn't fail, but there is no issue key yet
+		finishedEventData.Result = keptnv2.ResultWarning
+		finishedEventData.Message = "Ticket could not be created yet and is retried in the background: " + ticketErr.Error()
+		ticketErr = nil
+	} else if ticketErr != nil {
+		finishedEventData.Status = keptnv2.StatusErrored
+		finishedEventData.Result = keptnv2.ResultFailed
+		finishedEventData.Message = ticketErr.Error()
+	} else {
+		finishedEventData.Message = "Created ticket " + ticket.Key
+		finishedEventData.Jira.IssueKey = ticket.Key
+		finishedEventData.Jira.IssueURL = ticket.URL
+	}
+
+	_, err = myKeptn.SendTaskFinishedEvent(finishedEventData, ServiceName)
+	if err != nil {
+		return fmt.Errorf("could not send jira.finished event: %w", err)
+	}
+
+	return ticketErr
 }
 
 //*******************************
@@ -60,10 +179,11 @@ func HandleProblemEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event,
 *   PROBLEM SPECIFIC METHODS
 *********************************************/
 
-func createCustomPropertiesForProblemEvents(myKeptn *keptnv2.Keptn, data *keptnv2.ActionFinishedEventData, ticketURL string) map[string]string {
+func createCustomPropertiesForProblemEvents(myKeptn *keptnv2.Keptn, data *ProblemEventData, ticketURL string) map[string]string {
 	var customProperties = make(map[string]string)
 
-	customProperties["Result"] = string(data.Result)
+	customProperties["Problem"] = getProblemTitle(data)
+	customProperties["Problem ID"] = data.PID
 	customProperties["Keptn Project"] = data.EventData.GetProject()
 	customProperties["Keptn Service"] = data.EventData.GetService()
 	customProperties["Keptn Stage"] = data.EventData.GetStage()
@@ -80,7 +200,7 @@ func createCustomPropertiesForProblemEvents(myKeptn *keptnv2.Keptn, data *keptnv
 //
 // Note: This method might be replaced in future if we can send events that the dynatrace-service consumes
 // As the dynatrace-service contains nice helper methods to send events.
-func sendEventForProblemEvents(eventDestination string, eventType string, ticketURL string, data *keptnv2.ActionFinishedEventData, myKeptn *keptnv2.Keptn) {
+func sendEventForProblemEvents(eventDestination string, eventType string, ticketURL string, data *ProblemEventData, myKeptn *keptnv2.Keptn) {
 	log.Println("[eventhandlers.go] Sending event to:", eventDestination, " as type:", eventType)
 
 	// Split ticketURL by last forward slash to get the project key
@@ -89,10 +209,6 @@ func sendEventForProblemEvents(eventDestination string, eventType string, ticket
 	// Send Dynatrace Event
 	if eventDestination == "dynatrace" && os.Getenv("DT_TENANT") != "" && os.Getenv("DT_API_TOKEN") != "" {
 
-		dynatraceTenant := os.Getenv("DT_TENANT")
-		dynatraceAPIToken := os.Getenv("DT_API_TOKEN")
-		dynatraceAPITokenHeader := "Api-Token " + dynatraceAPIToken
-
 		// Build data
 		var dtInfoEvent = new(DtInfoEvent)
 		dtInfoEvent.EventType = eventType
@@ -103,119 +219,152 @@ func sendEventForProblemEvents(eventDestination string, eventType string, ticket
 		customProperties := createCustomPropertiesForProblemEvents(myKeptn, data, ticketURL)
 		dtInfoEvent.CustomProperties = customProperties
 
-		//Encode the data
-		jsonString, _ := json.Marshal(dtInfoEvent)
-
-		client := &http.Client{}
-
-		dtTenantURL := "https://" + dynatraceTenant + "/api/v1/events"
-		req, _ := http.NewRequest("POST", dtTenantURL, bytes.NewReader(jsonString))
-		req.Header.Add("accept", "application/json")
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("Authorization", dynatraceAPITokenHeader)
-
-		// Send Request
-		resp, err := client.Do(req)
-
-		//Handle Error
-		if err != nil {
-			log.Fatalf("[eventhandlers.go] An Error Occured Sending POSt to JIRA %v", err)
-		}
-		defer resp.Body.Close()
-
-		//Read the response body
-		_, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatalln(err)
+		// Failed events are retried through the outbox
+		if retryable, err := sendDynatraceEvent(dtInfoEvent); err != nil {
+			log.Println("[eventhandlers.go] An Error Occured Sending Event to Dynatrace:", err)
+			if retryable {
+				enqueueOutboxEntry(outboxKindDynatraceEvent, "", dtInfoEvent, err)
+			}
 		}
-
 	}
 
 }
 
-func createJIRATicketForProblem(myKeptn *keptnv2.Keptn, data *keptnv2.ActionFinishedEventData) string {
+func createJIRATicketForProblem(myKeptn *keptnv2.Keptn, data *ProblemEventData, details *JiraDetails) (*Ticket, error) {
 
 	log.Println("[eventhandlers.go] Creating JIRA Body details for problem...")
 
 	// Build summary field (JIRA ticket title)
-	summary := "[PROBLEM] " + data.GetProject() + " - " + data.GetService() + " - " + data.GetStage() + " - Result: " + string(data.Result)
-
-	description := "||*PROBLEM Status*||*Project*||*Service*||*Stage*||\n"
-	/* Add nice JIRA icons
-	* Emojis via API don't follow the UI standard
-	* (/) = :check_mark:
-	* (!) = :warning:
-	* (x) = :cross_mark:
-	 */
-	stringResult := string(data.Result)
-	result := ""
-	if stringResult == "pass" {
-		result += stringResult + " (/)"
-	} else if stringResult == "warning" {
-		result += stringResult + " (!)"
-	} else if stringResult == "fail" {
-		result += stringResult + " (x)"
-	} else {
-		result += stringResult
-	}
-	description += "|" + result + "|" + data.GetProject() + "|" + data.GetService() + "|" + data.GetStage() + "|\n\n"
+	summary := "[PROBLEM] " + data.GetProject() + " - " + data.GetService() + " - " + data.GetStage() + " - " + getProblemTitle(data)
+
+	description := "||*Problem*||*State*||*Project*||*Service*||*Stage*||\n"
+	description += "|" + escapeJIRATableCell(getProblemTitle(data)) + "|" + getProblemStateWithIcon(data) + "|" + data.GetProject() + "|" + data.GetService() + "|" + data.GetStage() + "|\n\n"
+
+	// Add what is impacted and why
+	description += createProblemDetailsForProblem(data)
 
 	// Add Message
-	description += "Message: " + data.Message + "\n\n"
+	if data.Message != "" {
+		description += "Message: " + data.Message + "\n\n"
+	}
 
 	// Add Keptn Context
 	description += "Keptn Context ID: " + myKeptn.KeptnContext + "\n"
 
+	// Add link to the problem in the monitoring tool
+	if data.ProblemURL != "" {
+		description += "[Link To Problem|" + data.ProblemURL + "]\n"
+	}
+
 	// Add link to Keptn Bridge
 	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + data.EventData.GetProject() + "/sequence/" + myKeptn.KeptnContext
 	description += "[Link To Keptn's Bridge|" + bridgeURL + "]"
 
+	// Apply user supplied templates, if any
+	templateData := newTicketTemplateData(myKeptn, data, summary, description)
+	summary, description = renderTicketTemplate("problem", templateData, details)
+
 	// Build map of labels which we take from the cloudevent, which we then attach to the JIRA ticket
 	labels := createJIRALabelsForProblemEvents(data)
 
-	// Send the POST to JIRA
-	issueKey := createJIRATicket(summary, description, labels)
-	return issueKey
-}
-
-func createJIRALabelsForProblemEvents(data *keptnv2.ActionFinishedEventData) []string {
-	//[]string{"foo:bar", "this:that"}
-	labels := []string{}
+	ticket := &Ticket{
+		Summary:      summary,
+		Description:  description,
+		Labels:       labels,
+		CustomFields: getJIRACustomFields(templateData, details),
+		Priority:     getJIRAPriority(newPriorityInputForProblem(data), details),
+		Attachments:  newEventAttachments(myKeptn, details),
+	}
 
-	// Add Keptn Project, Service and Stage as labels
-	// JIRA labels don't accept spaces so convert spaces to dashes
-	value := strings.ReplaceAll(data.EventData.GetProject(), " ", "-")
-	labels = append(labels, "keptn_project:"+value)
+	setTicketComponentsAndVersions(ticket, templateData, details)
+	routeTicket(ticket, templateData, details)
+	resolveTicketAssignee(ticket, templateData, details)
 
-	value = strings.ReplaceAll(data.EventData.GetService(), " ", "-")
-	labels = append(labels, "keptn_service:"+value)
+	// Send the POST to JIRA
+	return createJIRATicket(myKeptn.KeptnContext, ticket, details)
+}
 
-	value = strings.ReplaceAll(data.EventData.GetStage(), " ", "-")
-	labels = append(labels, "keptn_service:"+value)
+// Problems without title (e.g. from tools which only send an ID) are named after their ID
+func getProblemTitle(data *ProblemEventData) string {
+	if data.ProblemTitle != "" {
+		return data.ProblemTitle
+	}
+	if data.PID != "" {
+		return "Problem " + data.PID
+	}
+	return "Problem " + data.ProblemID
+}
 
-	// Add result as a label (pass, warning or fail)
-	labels = append(labels, "keptn_result:"+string(data.Result))
+func getProblemStateWithIcon(data *ProblemEventData) string {
+	state := strings.ToUpper(data.State)
+	if state == "" {
+		state = "OPEN"
+	}
+	if data.IsClosed() {
+		return state + " (/)"
+	}
+	return state + " (x)"
+}
 
-	for labelKey, labelValue := range data.Labels {
-		// Replace spaces with dashes for the Key and Value
-		labelKeyClean := strings.ReplaceAll(labelKey, " ", "-")
-		labelValueClean := strings.ReplaceAll(labelValue, " ", "-")
+// Builds the impacted entity, impact, severity and root cause of the problem, skipping everything the monitoring tool didn't send
+func createProblemDetailsForProblem(data *ProblemEventData) string {
+	details := ""
+	switch {
+	case data.PID != "" && data.ProblemID != "" && data.PID != data.ProblemID:
+		details += "Problem ID: " + data.PID + " (" + data.ProblemID + ")\n"
+	case data.PID != "":
+		details += "Problem ID: " + data.PID + "\n"
+	case data.ProblemID != "":
+		details += "Problem ID: " + data.ProblemID + "\n"
+	}
+	if data.ImpactedEntity != "" {
+		details += "Impacted Entity: " + data.ImpactedEntity + "\n"
+	}
+	if impactLevel := data.GetImpactLevel(); impactLevel != "" {
+		details += "Impact Level: " + impactLevel + "\n"
+	}
+	if severityLevel := data.GetSeverityLevel(); severityLevel != "" {
+		details += "Severity Level: " + severityLevel + "\n"
+	}
+	if data.Tags != "" {
+		details += "Tags: " + data.Tags + "\n"
+	}
+	if details != "" {
+		details += "\n"
+	}
 
-		//Stick the cleaned key and value back together
-		cleanKeyValueLabel := fmt.Sprint(labelKeyClean, ":", labelValueClean)
+	if rootCause := data.ProblemDetails.GetRootCause(); rootCause != nil {
+		details += "h4. Root Cause\n"
+		details += "*" + rootCause.EventType + "* on " + rootCause.EntityName + "\n\n"
+	}
 
-		// Skip labels that are too long for JIRA to handle
-		// Max length is 255 chars
-		if len(cleanKeyValueLabel) > 255 {
-			log.Println("[eventhandlers.go] Skipping label: ", cleanKeyValueLabel, ": Reason: label too long. JIRA accepts labels of max 255 chars and this label has:", len(cleanKeyValueLabel))
+	if len(data.ProblemDetails.RankedEvents) > 0 {
+		details += "||*Event*||*Entity*||*Severity*||*Impact*||*Root Cause*||\n"
+		for _, event := range data.ProblemDetails.RankedEvents {
+			rootCause := ""
+			if event.IsRootCause {
+				rootCause = "(!)"
+			}
+			details += "|" + escapeJIRATableCell(event.EventType) +
+				"|" + escapeJIRATableCell(event.EntityName) +
+				"|" + escapeJIRATableCell(event.SeverityLevel) +
+				"|" + escapeJIRATableCell(event.ImpactLevel) +
+				"|" + rootCause + "|\n"
 		}
-		labels = append(labels, fmt.Sprint(labelKeyClean, ":", labelValueClean)) // Append this "key":"value" using Sprint so as to not add spaces
+		details += "\n"
 	}
 
-	return labels
+	if data.ProblemDetails.Description != "" {
+		details += "h4. Details\n" + data.ProblemDetails.Description + "\n\n"
+	}
+	return details
+}
+
+func createJIRALabelsForProblemEvents(data *ProblemEventData) []string {
+	return createJIRALabelsForEventData(&data.EventData)
 }
 
-func createAttachRulesForProblemEvents(data *keptnv2.ActionFinishedEventData) DtAttachRules {
+func createAttachRulesForProblemEvents(data *ProblemEventData) DtAttachRules {
 	attachRule := DtAttachRules{
 		TagRule: []DtTagRule{
 			{
@@ -244,6 +393,64 @@ func createAttachRulesForProblemEvents(data *keptnv2.ActionFinishedEventData) Dt
 	return attachRule
 }
 
+/********************************************
+*   JIRA TASK SPECIFIC METHODS
+*********************************************/
+
+func createJIRATicketForJiraTask(myKeptn *keptnv2.Keptn, data *JiraTriggeredEventData, details *JiraDetails) (*Ticket, error) {
+
+	log.Println("[eventhandlers.go] Creating JIRA Body details for jira task...")
+
+	// Build summary field (JIRA ticket title)
+	summary := "[KEPTN] " + data.EventData.GetProject() + " - " + data.EventData.GetService() + " - " + data.EventData.GetStage()
+
+	description := "||*Project*||*Service*||*Stage*||\n"
+	description += "|" + data.EventData.GetProject() + "|" + data.EventData.GetService() + "|" + data.EventData.GetStage() + "|\n\n"
+
+	// Add Message
+	if data.EventData.Message != "" {
+		description += "Message: " + data.EventData.Message + "\n\n"
+	}
+
+	// Add Keptn Context
+	description += "Keptn Context ID: " + myKeptn.KeptnContext + "\n"
+
+	// Add link to Keptn Bridge
+	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + data.EventData.GetProject() + "/sequence/" + myKeptn.KeptnContext
+	description += "[Link To Keptn's Bridge|" + bridgeURL + "]"
+
+	// Apply user supplied templates, if any
+	templateData := newTicketTemplateData(myKeptn, data, summary, description)
+	summary, description = renderTicketTemplate(JiraTaskName, templateData, details)
+
+	// Summary and description in the triggered event win over everything else
+	if data.Jira.Summary != "" {
+		summary = data.Jira.Summary
+	}
+	if data.Jira.Description != "" {
+		description = data.Jira.Description
+	}
+
+	// Build map of labels which we take from the cloudevent, which we then attach to the JIRA ticket
+	labels := createJIRALabelsForEventData(&data.EventData)
+
+	ticket := &Ticket{
+		Summary:      summary,
+		Description:  description,
+		Labels:       labels,
+		CustomFields: getJIRACustomFields(templateData, details),
+		Priority:     getJIRAPriority(&PriorityInput{Result: string(data.Result), Stage: data.GetStage()}, details),
+		Attachments:  newEventAttachments(myKeptn, details),
+	}
+
+	setTicketComponentsAndVersions(ticket, templateData, details)
+	routeTicket(ticket, templateData, details)
+	resolveTicketAssignee(ticket, templateData, details)
+
+	// Send the POST to JIRA
+	return createJIRATicket(myKeptn.KeptnContext, ticket, details)
+}
+
 /********************************************
 *   EVALUATION.FINISHED SPECIFIC METHODS
 *********************************************/
@@ -262,10 +469,6 @@ func sendEventForEvaluationFinishedEvents(eventDestination string, eventType str
 	// Send Dynatrace Event
 	if eventDestination == "dynatrace" && os.Getenv("DT_TENANT") != "" && os.Getenv("DT_API_TOKEN") != "" {
 
-		dynatraceTenant := os.Getenv("DT_TENANT")
-		dynatraceAPIToken := os.Getenv("DT_API_TOKEN")
-		dynatraceAPITokenHeader := "Api-Token " + dynatraceAPIToken
-
 		// Build data
 		var dtInfoEvent = new(DtInfoEvent)
 		dtInfoEvent.EventType = eventType
@@ -276,32 +479,13 @@ func sendEventForEvaluationFinishedEvents(eventDestination string, eventType str
 		customProperties := createCustomPropertiesForEvaluationFinishedEvents(myKeptn, data, ticketURL)
 		dtInfoEvent.CustomProperties = customProperties
 
-		//Encode the data
-		jsonString, _ := json.Marshal(dtInfoEvent)
-
-		client := &http.Client{}
-
-		dtTenantURL := "https://" + dynatraceTenant + "/api/v1/events"
-		req, _ := http.NewRequest("POST", dtTenantURL, bytes.NewReader(jsonString))
-		req.Header.Add("accept", "application/json")
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("Authorization", dynatraceAPITokenHeader)
-
-		// Send Request
-		resp, err := client.Do(req)
-
-		//Handle Error
-		if err != nil {
-			log.Fatalf("[eventhandlers.go] An Error Occured Sending POSt to JIRA %v", err)
+		// Failed events are retried through the outbox
+		if retryable, err := sendDynatraceEvent(dtInfoEvent); err != nil {
+			log.Println("[eventhandlers.go] An Error Occured Sending Event to Dynatrace:", err)
+			if retryable {
+				enqueueOutboxEntry(outboxKindDynatraceEvent, "", dtInfoEvent, err)
+			}
 		}
-		defer resp.Body.Close()
-
-		//Read the response body
-		_, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
 	}
 
 }
@@ -353,19 +537,189 @@ func createCustomPropertiesForEvaluationFinishedEvents(myKeptn *keptnv2.Keptn, d
 }
 
 func createJIRALabelsForEvaluationFinishedEvents(data *keptnv2.EvaluationFinishedEventData) []string {
+	return createJIRALabelsForEventData(&data.EventData)
+}
+
+func createJIRATicketForEvaluationFinished(myKeptn *keptnv2.Keptn, data *keptnv2.EvaluationFinishedEventData, details *JiraDetails) (*Ticket, error) {
+
+	log.Println("[eventhandlers.go] Creating JIRA Body details for evaluation.finished...")
+
+	// Build summary field (JIRA ticket title)
+	stringResult := string(data.Result)
+	summary := "[EVALUATION] " + data.EventData.GetProject() + " - " + data.EventData.GetService() + " - " + data.EventData.GetStage() + " - Result: " + stringResult
+
+	// Build description field (JIRA ticket body)
+	// Build result table
+	description := "||*Result*||*Score*||\n"
+	result := getResultWithIcon(stringResult)
+	description += "|" + result + "|" + fmt.Sprint(data.Evaluation.Score) + "|" + "\n\n"
+
+	// Add one row per SLI
+	description += createSLITableForEvaluationFinished(data)
+
+	// Add Start Time and End Time
+	description += "Start Time: " + data.Evaluation.TimeStart + "\n"
+	description += "End Time: " + data.Evaluation.TimeEnd + "\n"
+
+	// Add Keptn Context
+	description += "Keptn Context ID: " + myKeptn.KeptnContext + "\n"
+
+	description += "Message: " + data.EventData.Message + "\n"
+
+	// Add link to Keptn Bridge
+	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + data.EventData.GetProject() + "/sequence/" + myKeptn.KeptnContext
+	description += "[Link To Keptn's Bridge|" + bridgeURL + "]"
+
+	// Apply user supplied templates, if any
+	templateData := newTicketTemplateData(myKeptn, data, summary, description)
+	summary, description = renderTicketTemplate("evaluation", templateData, details)
+
+	// Build map of labels which we take from the cloudevent, which we then attach to the JIRA ticket
+	labels := createJIRALabelsForEvaluationFinishedEvents(data)
+
+	ticket := &Ticket{
+		Summary:      summary,
+		Description:  description,
+		Labels:       labels,
+		CustomFields: getJIRACustomFields(templateData, details),
+		Priority:     getJIRAPriority(newPriorityInputForEvaluation(data), details),
+		Attachments:  newEvaluationAttachments(myKeptn, data, details),
+	}
+
+	setTicketComponentsAndVersions(ticket, templateData, details)
+	routeTicket(ticket, templateData, details)
+	resolveTicketAssignee(ticket, templateData, details)
+
+	// Send the POST to JIRA
+	return createJIRATicket(myKeptn.KeptnContext, ticket, details)
+}
+
+// Builds a table with the value, criteria and result of every SLI of the evaluation
+// Failed SLIs are highlighted in red. Returns an empty string if there are no indicator results
+func createSLITableForEvaluationFinished(data *keptnv2.EvaluationFinishedEventData) string {
+	if data == nil || len(data.Evaluation.IndicatorResults) == 0 {
+		return ""
+	}
+
+	table := "||*SLI*||*Value*||*Pass Criteria*||*Warning Criteria*||*Result*||\n"
+	for _, indicator := range data.Evaluation.IndicatorResults {
+		if indicator == nil {
+			continue
+		}
+
+		name := indicator.DisplayName
+		value := ""
+		if indicator.Value != nil {
+			if name == "" {
+				name = indicator.Value.Metric
+			}
+			if indicator.Value.Success {
+				value = fmt.Sprint(indicator.Value.Value)
+			} else {
+				// The value couldn't be retrieved, show the reason instead
+				value = "n/a " + indicator.Value.Message
+			}
+		}
+		if indicator.KeySLI {
+			name += " (key SLI)"
+		}
+
+		if indicator.Status == "fail" {
+			name = "{color:red}*" + name + "*{color}"
+			value = "{color:red}*" + value + "*{color}"
+		}
+
+		table += "|" + escapeJIRATableCell(name) +
+			"|" + escapeJIRATableCell(value) +
+			"|" + escapeJIRATableCell(joinSLICriteria(indicator.PassTargets)) +
+			"|" + escapeJIRATableCell(joinSLICriteria(indicator.WarningTargets)) +
+			"|" + getResultWithIcon(indicator.Status) + "|\n"
+	}
+
+	return table + "\n"
+}
+
+func joinSLICriteria(targets []*keptnv2.SLITarget) string {
+	criteria := []string{}
+	for _, target := range targets {
+		if target != nil {
+			criteria = append(criteria, target.Criteria)
+		}
+	}
+	return strings.Join(criteria, ", ")
+}
+
+// Pipes would start a new cell and empty cells collapse in JIRA, so replace them
+func escapeJIRATableCell(cell string) string {
+	cell = strings.ReplaceAll(cell, "|", "/")
+	if strings.TrimSpace(cell) == "" {
+		return " "
+	}
+	return cell
+}
+
+/**************************************
+*         GENERIC METHODS
+***************************************/
+// Shared Function between evaluations and problem events to send an event to Dynatrace
+// Returns whether it makes sense to retry the call if it failed
+func sendDynatraceEvent(dtInfoEvent *DtInfoEvent) (bool, error) {
+	dynatraceTenant := os.Getenv("DT_TENANT")
+	dynatraceAPIToken := os.Getenv("DT_API_TOKEN")
+	dynatraceAPITokenHeader := "Api-Token " + dynatraceAPIToken
+
+	//Encode the data
+	jsonString, err := json.Marshal(dtInfoEvent)
+	if err != nil {
+		return false, err
+	}
+
+	client := &http.Client{}
+
+	dtTenantURL := "https://" + dynatraceTenant + "/api/v1/events"
+	req, err := http.NewRequest("POST", dtTenantURL, bytes.NewReader(jsonString))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", dynatraceAPITokenHeader)
+
+	// Send Request
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	//Read the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return isRetryableStatusCode(resp.StatusCode), fmt.Errorf("dynatrace returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return false, nil
+}
+
+// Shared Function to build the labels of a ticket from the Keptn event data
+func createJIRALabelsForEventData(data *keptnv2.EventData) []string {
 	//[]string{"foo:bar", "this:that"}
 	labels := []string{}
 
 	// Add Keptn Project, Service and Stage as labels
 	// JIRA labels don't accept spaces so convert spaces to dashes
-	value := strings.ReplaceAll(data.EventData.GetProject(), " ", "-")
+	value := strings.ReplaceAll(data.GetProject(), " ", "-")
 	labels = append(labels, "keptn_project:"+value)
 
-	value = strings.ReplaceAll(data.EventData.GetService(), " ", "-")
+	value = strings.ReplaceAll(data.GetService(), " ", "-")
 	labels = append(labels, "keptn_service:"+value)
 
-	value = strings.ReplaceAll(data.EventData.GetStage(), " ", "-")
-	labels = append(labels, "keptn_service:"+value)
+	value = strings.ReplaceAll(data.GetStage(), " ", "-")
+	labels = append(labels, "keptn_stage:"+value)
 
 	// Add result as a label (pass, warning or fail)
 	labels = append(labels, "keptn_result:"+string(data.Result))
@@ -382,118 +736,164 @@ func createJIRALabelsForEvaluationFinishedEvents(data *keptnv2.EvaluationFinishe
 		// Max length is 255 chars
 		if len(cleanKeyValueLabel) > 255 {
 			log.Println("[eventhandlers.go] Skipping label: ", cleanKeyValueLabel, ": Reason: label too long. JIRA accepts labels of max 255 chars and this label has:", len(cleanKeyValueLabel))
+			continue
 		}
-		labels = append(labels, fmt.Sprint(labelKeyClean, ":", labelValueClean)) // Append this "key":"value" using Sprint so as to not add spaces
+		labels = append(labels, cleanKeyValueLabel)
 	}
 
 	return labels
 }
 
-func createJIRATicketForEvaluationFinished(myKeptn *keptnv2.Keptn, data *keptnv2.EvaluationFinishedEventData) string {
+// Shared Function between evaluations and problem events to create a JIRA ticket
+// By this point, summary and description are correctly formulated
+// Depending on the type of ticket so this function can be shared
+// As it just sends the POST to the configured tracker
+//
+// If an open ticket already exists for the Keptn context (e.g. because the distributor
+// redelivered the event), the details are added as a comment to that ticket instead
+func createJIRATicket(keptnContext string, ticket *Ticket, details *JiraDetails) (*Ticket, error) {
+	tracker, err := newTracker(details)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Println("[eventhandlers.go] Creating JIRA Body details for evaluation.finished...")
+	existingTicket, err := tracker.FindOpenTicket(createJIRAContextLabel(keptnContext))
+	if err != nil {
+		log.Println("[eventhandlers.go] Could not search for existing ticket, creating a new one:", err)
+	} else if existingTicket != nil {
+		log.Println("[eventhandlers.go] Found open ticket for Keptn context", keptnContext, ":", existingTicket.Key)
+		addTicketComment(tracker, existingTicket.Key, "h4. "+ticket.Summary+"\n"+ticket.Description)
+		addTicketAttachments(tracker, existingTicket.Key, ticket.Attachments)
+		return existingTicket, nil
+	}
 
-	// Build summary field (JIRA ticket title)
-	stringResult := string(data.Result)
-	summary := "[EVALUATION] " + data.EventData.GetProject() + " - " + data.EventData.GetService() + " - " + data.EventData.GetStage() + " - Result: " + stringResult
+	// Attach the Keptn context so that follow-up events can find this ticket again
+	ticket.Labels = append(ticket.Labels, createJIRAContextLabel(keptnContext))
 
-	// Build description field (JIRA ticket body)
-	// Build result table
-	description := "||*Result*||*Score*||\n"
-	/* Add nice JIRA icons
-	 * Emojis via API don't follow the UI standard
-	 * (/) = :check_mark:
-	 * (!) = :warning:
-	 * (x) = :cross_mark:
-	 */
-	result := ""
-	if stringResult == "pass" {
-		result += stringResult + " (/)"
-	} else if stringResult == "warning" {
-		result += stringResult + " (!)"
-	} else if stringResult == "fail" {
-		result += stringResult + " (x)"
-	} else {
-		result += stringResult
+	return createNewTicket(tracker, ticket, details)
+}
+
+// Creates the ticket without looking for an existing ticket first
+// Returns a TicketQueuedError if the ticket is retried through the outbox
+func createNewTicket(tracker Tracker, ticket *Ticket, details *JiraDetails) (*Ticket, error) {
+	ticket.Labels = append(ticket.Labels, details.Labels...)
+
+	if err := tracker.CreateTicket(ticket); err != nil {
+		// The tracker might be down or rate limiting us, retry later through the outbox
+		if isRetryableTrackerError(err) {
+			enqueueOutboxEntry(outboxKindTicket, tracker.Name(), ticket, err)
+			return nil, &TicketQueuedError{Err: err}
+		}
+		return nil, err
 	}
-	description += "|" + result + "|" + fmt.Sprint(data.Evaluation.Score) + "|" + "\n\n"
 
-	// Add Start Time and End Time
-	description += "Start Time: " + data.Evaluation.TimeStart + "\n"
-	description += "End Time: " + data.Evaluation.TimeEnd + "\n"
+	log.Println("[eventhandlers.go] Created ticket successfully: ", ticket.Key)
+	addTicketAttachments(tracker, ticket.Key, ticket.Attachments)
+	return ticket, nil
+}
 
-	// Add Keptn Context
-	description += "Keptn Context ID: " + myKeptn.KeptnContext + "\n"
+// Label of problem tickets whose remediation failed, so they can be found in filters and boards
+const remediationFailedLabel = "keptn_remediation_failed"
 
-	description += "Message: " + data.EventData.Message + "\n"
+// The time attribute is optional in CloudEvents, events without it are stamped with the time they are processed
+func formatEventTime(incomingEvent cloudevents.Event) string {
+	eventTime := incomingEvent.Time()
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	return eventTime.UTC().Format(time.RFC3339)
+}
 
-	// Add link to Keptn Bridge
-	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + data.EventData.GetProject() + "/sequence/" + myKeptn.KeptnContext
-	description += "[Link To Keptn's Bridge|" + bridgeURL + "]"
+// JIRA labels don't accept spaces, Keptn contexts are UUIDs so this is safe to use as is
+func createJIRAContextLabel(keptnContext string) string {
+	return "keptn_context:" + keptnContext
+}
 
-	// Build map of labels which we take from the cloudevent, which we then attach to the JIRA ticket
-	labels := createJIRALabelsForEvaluationFinishedEvents(data)
+// Finds the open ticket for the Keptn context, adds the comment and moves it
+// through the configured resolve transition (JIRA_RESOLVE_TRANSITION)
+func resolveTicketForContext(keptnContext string, comment string, details *JiraDetails) error {
+	tracker, err := newTracker(details)
+	if err != nil {
+		return err
+	}
 
-	// Send the POST to JIRA
-	issueKey := createJIRATicket(summary, description, labels)
+	ticket, err := tracker.FindOpenTicket(createJIRAContextLabel(keptnContext))
+	if err != nil {
+		return err
+	}
+	if ticket == nil {
+		log.Println("[eventhandlers.go] No open ticket found for Keptn context", keptnContext, ". Nothing to resolve")
+		return nil
+	}
 
-	return issueKey
-}
+	addTicketComment(tracker, ticket.Key, comment)
 
-/**************************************
-*         GENERIC METHODS
-***************************************/
-// Shared Function between evaluations and problem events to create a JIRA ticket
-// By this point, summary and description are correctly formulated
-// Depending on the type of ticket so this function can be shared
-// As it just sends the POST to JIRA
-func createJIRATicket(summary string, description string, labels []string) string {
-	tp := jira.BasicAuthTransport{
-		Username: JIRA_DETAILS.Username,
-		Password: JIRA_DETAILS.APIToken,
+	if err := tracker.Transition(ticket.Key, details.ResolveTransition); err != nil {
+		return err
 	}
+	log.Println("[eventhandlers.go] Resolved ticket successfully: ", ticket.Key)
+	return nil
+}
 
-	jiraClient, err := jira.NewClient(tp.Client(), JIRA_DETAILS.BaseURL)
+// Adds the comment to the open ticket for the Keptn context, if there is one
+func commentTicketForContext(keptnContext string, comment string, details *JiraDetails) error {
+	tracker, err := newTracker(details)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	i := jira.Issue{
-		Fields: &jira.IssueFields{
-			Assignee: &jira.User{
-				AccountID: JIRA_DETAILS.AssigneeID,
-			},
-			Reporter: &jira.User{
-				AccountID: JIRA_DETAILS.ReporterID,
-			},
-			Description: description,
-			Type: jira.IssueType{
-				Name: JIRA_DETAILS.IssueType,
-			},
-			Project: jira.Project{
-				Key: JIRA_DETAILS.ProjectKey,
-			},
-			Summary: summary,
-			Labels:  labels,
-		},
+	ticket, err := tracker.FindOpenTicket(createJIRAContextLabel(keptnContext))
+	if err != nil {
+		return err
+	}
+	if ticket == nil {
+		log.Println("[eventhandlers.go] No open ticket found for Keptn context", keptnContext, ". Nothing to comment")
+		return nil
 	}
 
-	// Create ticket
-	issue, response, err := jiraClient.Issue.Create(&i)
+	addTicketComment(tracker, ticket.Key, comment)
+	return nil
+}
 
+// Finds the open ticket for the Keptn context, adds the comment and the remediation failed label
+// and moves it through the escalation transition (JIRA_ESCALATE_TRANSITION), if one is configured
+func escalateTicketForContext(keptnContext string, comment string, details *JiraDetails) error {
+	tracker, err := newTracker(details)
 	if err != nil {
+		return err
+	}
 
-		data, err2 := ioutil.ReadAll(response.Body)
-		if err != nil {
-			log.Println(err2)
-			log.Println(string(data))
-		}
-		log.Println(err)
-		log.Println(string(data))
-	} else {
-		log.Println("[eventhandlers.go] Created ticket successfully: ", issue.Key)
+	ticket, err := tracker.FindOpenTicket(createJIRAContextLabel(keptnContext))
+	if err != nil {
+		return err
+	}
+	if ticket == nil {
+		log.Println("[eventhandlers.go] No open ticket found for Keptn context", keptnContext, ". Nothing to escalate")
+		return nil
 	}
 
-	return issue.Key
+	addTicketComment(tracker, ticket.Key, comment)
 
+	if err := tracker.UpdateLabels(ticket.Key, []string{remediationFailedLabel}, nil); err != nil {
+		return err
+	}
+
+	if details.EscalateTransition != "" {
+		if err := tracker.Transition(ticket.Key, details.EscalateTransition); err != nil {
+			return err
+		}
+	}
+	log.Println("[eventhandlers.go] Escalated ticket successfully: ", ticket.Key)
+	return nil
+}
+
+func addTicketComment(tracker Tracker, key string, body string) {
+	if err := tracker.AddComment(key, body); err != nil {
+		log.Println("[eventhandlers.go] Could not add comment to ticket", key, ":", err)
+		if isRetryableTrackerError(err) {
+			enqueueOutboxEntry(outboxKindComment, tracker.Name(), &OutboxComment{Key: key, Body: body}, err)
+		}
+		return
+	}
+	log.Println("[eventhandlers.go] Added comment to ticket successfully: ", key)
 }
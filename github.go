@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHub rejects issues with labels longer than this
+const gitHubMaxLabelLength = 50
+
+// GitHubDetails holds the settings of the GitHub Issues tracker
+type GitHubDetails struct {
+	// APIURL is https://api.github.com or https://<host>/api/v3 for GitHub Enterprise Server
+	APIURL string
+	Token  string
+	// Repository is owner/repo
+	Repository string
+	// Assignee is a GitHub login
+	Assignee string
+}
+
+// gitHubTracker creates tickets as GitHub Issues through the REST API
+// Issue numbers are only unique per repository, so the keys of tickets are owner/repo#number
+type gitHubTracker struct {
+	client  *http.Client
+	details GitHubDetails
+	// repositories are searched for open tickets: the default repository and the ones of the routing rules
+	repositories []string
+}
+
+type gitHubIssue struct {
+	Number    int           `json:"number"`
+	HTMLURL   string        `json:"html_url"`
+	Title     string        `json:"title"`
+	State     string        `json:"state"`
+	Labels    []gitHubLabel `json:"labels"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type gitHubLabel struct {
+	Name string `json:"name"`
+}
+
+func newGitHubTracker(details *JiraDetails) (*gitHubTracker, error) {
+	if details.GitHub.Token == "" || !strings.Contains(details.GitHub.Repository, "/") {
+		return nil, &TrackerClientError{Tracker: "GitHub", Err: errors.New("GITHUB_TOKEN and GITHUB_REPOSITORY (owner/repo) are required")}
+	}
+
+	repositories := []string{details.GitHub.Repository}
+	for _, rule := range details.Routes {
+		if strings.Contains(rule.ProjectKey, "/") {
+			repositories = appendUnique(repositories, rule.ProjectKey)
+		}
+	}
+
+	return &gitHubTracker{
+		client:       &http.Client{Timeout: 30 * time.Second},
+		details:      details.GitHub,
+		repositories: repositories,
+	}, nil
+}
+
+func (t *gitHubTracker) Name() string {
+	return trackerGitHub
+}
+
+func (t *gitHubTracker) CreateTicket(ticket *Ticket) error {
+	if ticket.Project == "" {
+		ticket.Project = t.details.Repository
+	}
+
+	// Creating the issue would fail for the whole ticket if a single label is too long
+	labels := []string{}
+	for _, label := range ticket.Labels {
+		if len(label) > gitHubMaxLabelLength {
+			log.Println("[github.go] Skipping label: ", label, ": Reason: label too long. GitHub accepts labels of max", gitHubMaxLabelLength, "chars")
+			continue
+		}
+		labels = append(labels, label)
+	}
+
+	// GitHub has no priorities, so the priority becomes a label
+	if ticket.Priority != "" {
+		labels = append(labels, "priority:"+ticket.Priority)
+	}
+
+	request := map[string]interface{}{
+		"title":  ticket.Summary,
+		"body":   convertWikiMarkupToMarkdown(ticket.Description),
+		"labels": labels,
+	}
+	if ticket.Assignee != "" {
+		request["assignees"] = []string{ticket.Assignee}
+	}
+
+	issue := &gitHubIssue{}
+	if err := t.do(http.MethodPost, "/repos/"+ticket.Project+"/issues", request, issue, "create ticket"); err != nil {
+		return err
+	}
+
+	ticket.Key = newGitHubTicketKey(ticket.Project, issue.Number)
+	ticket.URL = issue.HTMLURL
+	return nil
+}
+
+func (t *gitHubTracker) UpdateLabels(key string, add []string, remove []string) error {
+	for _, label := range remove {
+		err := t.do(http.MethodDelete, t.issuePath(key)+"/labels/"+url.PathEscape(label), nil, nil, "remove label from ticket "+key)
+		// The label might have been removed by hand already
+		var requestError *TrackerRequestError
+		if err != nil && !(errors.As(err, &requestError) && requestError.StatusCode == http.StatusNotFound) {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		return t.do(http.MethodPost, t.issuePath(key)+"/labels", map[string][]string{"labels": add}, nil, "add labels to ticket "+key)
+	}
+	return nil
+}
+
+func (t *gitHubTracker) AddComment(key string, body string) error {
+	return t.do(http.MethodPost, t.issuePath(key)+"/comments", map[string]string{"body": convertWikiMarkupToMarkdown(body)}, nil, "add comment to ticket "+key)
+}
+
+// GitHub issues have no workflow, so open and reopen reopen the issue and every other transition closes it
+func (t *gitHubTracker) Transition(key string, transition string) error {
+	state := "closed"
+	if strings.EqualFold(transition, "open") || strings.EqualFold(transition, "reopen") {
+		state = "open"
+	}
+	return t.do(http.MethodPatch, t.issuePath(key), map[string]string{"state": state}, nil, "transition ticket "+key)
+}
+
+// Searches the default repository and the repositories of the routing rules, the newest open issue wins
+func (t *gitHubTracker) FindOpenTicket(label string) (*Ticket, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("labels", label)
+	query.Set("sort", "created")
+	query.Set("direction", "desc")
+	query.Set("per_page", "1")
+
+	var newest *gitHubIssue
+	newestRepository := ""
+	for _, repository := range t.repositories {
+		issues := []gitHubIssue{}
+		if err := t.do(http.MethodGet, "/repos/"+repository+"/issues?"+query.Encode(), nil, &issues, "search tickets in "+repository); err != nil {
+			return nil, err
+		}
+		if len(issues) > 0 && (newest == nil || issues[0].CreatedAt.After(newest.CreatedAt)) {
+			newest = &issues[0]
+			newestRepository = repository
+		}
+	}
+
+	if newest == nil {
+		return nil, nil
+	}
+	return newGitHubTicket(newestRepository, newest), nil
+}
+
+func (t *gitHubTracker) GetTicket(key string) (*Ticket, error) {
+	repository, _ := t.parseTicketKey(key)
+	issue := &gitHubIssue{}
+	if err := t.do(http.MethodGet, t.issuePath(key), nil, issue, "get ticket "+key); err != nil {
+		return nil, err
+	}
+	return newGitHubTicket(repository, issue), nil
+}
+
+func newGitHubTicket(repository string, issue *gitHubIssue) *Ticket {
+	ticket := &Ticket{
+		Key:     newGitHubTicketKey(repository, issue.Number),
+		URL:     issue.HTMLURL,
+		Project: repository,
+		Summary: issue.Title,
+		Status:  issue.State,
+	}
+	for _, l := range issue.Labels {
+		ticket.Labels = append(ticket.Labels, l.Name)
+	}
+	return ticket
+}
+
+func newGitHubTicketKey(repository string, number int) string {
+	return repository + "#" + strconv.Itoa(number)
+}
+
+// Splits owner/repo#number into repository and number
+// Keys without repository, e.g. of outbox entries written by older versions, belong to the default repository
+func (t *gitHubTracker) parseTicketKey(key string) (string, string) {
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return t.details.Repository, key
+}
+
+func (t *gitHubTracker) issuePath(key string) string {
+	repository, number := t.parseTicketKey(key)
+	return "/repos/" + repository + "/issues/" + number
+}
+
+// Sends a request to the GitHub API and decodes the response into result, if result is not nil
+func (t *gitHubTracker) do(method string, path string, body interface{}, result interface{}, operation string) error {
+	var requestBody *bytes.Buffer
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = bytes.NewBuffer(content)
+	} else {
+		requestBody = &bytes.Buffer{}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(t.details.APIURL, "/")+path, requestBody)
+	if err != nil {
+		return &TrackerClientError{Tracker: "GitHub", Err: err}
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+t.details.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &TrackerRequestError{Tracker: "GitHub", Operation: operation, Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return &TrackerRequestError{Tracker: "GitHub", Operation: operation, StatusCode: resp.StatusCode, Err: errors.New(strings.TrimSpace(string(respBody)))}
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return &TrackerRequestError{Tracker: "GitHub", Operation: operation, StatusCode: resp.StatusCode, Err: err}
+		}
+	}
+	return nil
+}
+
+/**************************************
+*   JIRA WIKI MARKUP TO MARKDOWN
+***************************************/
+
+var (
+	wikiHeadingPattern = regexp.MustCompile(`^h([1-6])\.\s+`)
+	wikiLinkPattern    = regexp.MustCompile(`\[([^\[\]|]+)\|([^\[\]]+)\]`)
+	wikiBoldPattern    = regexp.MustCompile(`\*([^*\s][^*\n]*?)\*`)
+	wikiColorPattern   = regexp.MustCompile(`\{color(:[^}]*)?\}`)
+	wikiIcons          = strings.NewReplacer("(/)", "✅", "(!)", "⚠️", "(x)", "❌")
+)
+
+// Converts the subset of JIRA wiki markup the jira-service writes (headings, tables, bold, links, colors, icons)
+// to GitHub flavored markdown
+func convertWikiMarkupToMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	converted := []string{}
+	inTable := false
+
+	for _, line := range lines {
+		// Links first, as the | in [text|url] would be taken as table cell separator otherwise
+		line = wikiLinkPattern.ReplaceAllString(line, "[$1]($2)")
+		line = wikiColorPattern.ReplaceAllString(line, "")
+		line = wikiBoldPattern.ReplaceAllString(line, "**$1**")
+		line = wikiIcons.Replace(line)
+
+		if match := wikiHeadingPattern.FindStringSubmatch(line); match != nil {
+			level, _ := strconv.Atoi(match[1])
+			line = strings.Repeat("#", level) + " " + strings.TrimPrefix(line, match[0])
+		}
+
+		if strings.HasPrefix(line, "||") {
+			cells := splitWikiTableRow(strings.TrimSuffix(strings.TrimPrefix(line, "||"), "||"), "||")
+			// Markdown tables need a blank line in front of them
+			if len(converted) > 0 && converted[len(converted)-1] != "" {
+				converted = append(converted, "")
+			}
+			converted = append(converted, "| "+strings.Join(cells, " | ")+" |")
+			converted = append(converted, strings.TrimSuffix(strings.Repeat("| --- ", len(cells)), " ")+" |")
+			inTable = true
+			continue
+		}
+
+		if strings.HasPrefix(line, "|") && inTable {
+			cells := splitWikiTableRow(strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|"), "|")
+			converted = append(converted, "| "+strings.Join(cells, " | ")+" |")
+			continue
+		}
+
+		inTable = false
+		converted = append(converted, line)
+	}
+
+	return strings.Join(converted, "\n")
+}
+
+// Splits a table row, ignoring separators inside of brackets
+func splitWikiTableRow(row string, separator string) []string {
+	cells := []string{}
+	depth := 0
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+		if depth == 0 && strings.HasPrefix(row[i:], separator) {
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			i += len(separator) - 1
+			continue
+		}
+		cell.WriteByte(row[i])
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestConvertWikiMarkupToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "empty text",
+			text: "",
+			want: "",
+		},
+		{
+			name: "headings",
+			text: "h1. Title\nh4. Root Cause",
+			want: "# Title\n#### Root Cause",
+		},
+		{
+			name: "table gets a separator row and a blank line in front",
+			text: "Evaluation\n||*Result*||*Score*||\n|fail (x)|42|",
+			want: "Evaluation\n\n| **Result** | **Score** |\n| --- | --- |\n| fail ❌ | 42 |",
+		},
+		{
+			name: "link inside of a table cell",
+			text: "||*Link*||*State*||\n|[Bridge|https://bridge.example.com]|done|",
+			want: "| **Link** | **State** |\n| --- | --- |\n| [Bridge](https://bridge.example.com) | done |",
+		},
+		{
+			name: "link with pipe in the url",
+			text: "[Link To Keptn's Bridge|https://bridge.example.com/?filter=a|b]",
+			want: "[Link To Keptn's Bridge](https://bridge.example.com/?filter=a|b)",
+		},
+		{
+			name: "icons become emojis",
+			text: "pass (/) warning (!) fail (x)",
+			want: "pass ✅ warning ⚠️ fail ❌",
+		},
+		{
+			name: "colors are dropped, bold inside of color is kept",
+			text: "{color:red}*response time*{color}",
+			want: "**response time**",
+		},
+		{
+			name: "colors are dropped, color inside of bold is kept bold",
+			text: "*{color:#ff0000}response time{color}*",
+			want: "**response time**",
+		},
+		{
+			name: "table ends with the first line which is no row",
+			text: "||*SLI*||\n|{color:red}*a*{color}|\n\nMessage: done",
+			want: "| **SLI** |\n| --- |\n| **a** |\n\nMessage: done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertWikiMarkupToMarkdown(tt.text); got != tt.want {
+				t.Errorf("convertWikiMarkupToMarkdown(%q)\n got: %q\nwant: %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
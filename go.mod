@@ -10,6 +10,7 @@ require (
 	github.com/go-openapi/validate v0.19.4 // indirect
 	github.com/golang/protobuf v1.3.2 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/google/uuid v1.2.0
 	github.com/hashicorp/golang-lru v0.5.3 // indirect
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/keptn/go-utils v0.8.5
@@ -21,5 +22,6 @@ require (
 	go.opencensus.io v0.22.0 // indirect
 	golang.org/x/net v0.0.0-20200202094626-16171245cfb2 // indirect
 	golang.org/x/sys v0.0.0-20200202164722-d101bd2416d5 // indirect
-	gopkg.in/andygrunwald/go-jira.v1 v1.8.0 // indirect
+	gopkg.in/andygrunwald/go-jira.v1 v1.8.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// JIRA deployments, selected with JIRA_DEPLOYMENT
+const (
+	jiraDeploymentCloud  = "cloud"
+	jiraDeploymentServer = "server"
+)
+
+// jiraTracker creates tickets through the JIRA REST API
+type jiraTracker struct {
+	client  *jira.Client
+	details JiraDetails
+}
+
+func newJIRATracker(details *JiraDetails) (*jiraTracker, error) {
+	jiraClient, err := newJIRAClient(details)
+	if err != nil {
+		return nil, err
+	}
+	return &jiraTracker{client: jiraClient, details: *details}, nil
+}
+
+func newJIRAClient(details *JiraDetails) (*jira.Client, error) {
+	httpClient, err := newJIRAHTTPClient(details)
+	if err != nil {
+		return nil, &TrackerClientError{Tracker: "JIRA", Err: err}
+	}
+
+	jiraClient, err := jira.NewClient(httpClient, details.BaseURL)
+	if err != nil {
+		return nil, &TrackerClientError{Tracker: "JIRA", Err: err}
+	}
+	return jiraClient, nil
+}
+
+func (t *jiraTracker) Name() string {
+	return trackerJIRA
+}
+
+func (t *jiraTracker) CreateTicket(ticket *Ticket) error {
+	if ticket.Project == "" {
+		ticket.Project = t.details.ProjectKey
+	}
+	if ticket.IssueType == "" {
+		ticket.IssueType = t.details.IssueType
+	}
+	if ticket.Reporter == "" {
+		ticket.Reporter = t.details.ReporterID
+	}
+
+	i := jira.Issue{
+		Fields: &jira.IssueFields{
+			Assignee:    t.newUser(ticket.Assignee),
+			Reporter:    t.newUser(ticket.Reporter),
+			Description: ticket.Description,
+			Type: jira.IssueType{
+				Name: ticket.IssueType,
+			},
+			Project: jira.Project{
+				Key: ticket.Project,
+			},
+			Summary: ticket.Summary,
+			Labels:  ticket.Labels,
+		},
+	}
+
+	if ticket.Priority != "" {
+		i.Fields.Priority = newJIRAPriority(ticket.Priority)
+	}
+	if t.details.CreateComponents || t.details.CreateVersions {
+		t.createMissingComponentsAndVersions(ticket)
+	}
+	for _, component := range ticket.Components {
+		i.Fields.Components = append(i.Fields.Components, &jira.Component{Name: component})
+	}
+	for _, version := range ticket.FixVersions {
+		i.Fields.FixVersions = append(i.Fields.FixVersions, &jira.FixVersion{Name: version})
+	}
+	for _, version := range ticket.AffectsVersions {
+		i.Fields.AffectsVersions = append(i.Fields.AffectsVersions, &jira.AffectsVersion{Name: version})
+	}
+	if len(ticket.CustomFields) > 0 {
+		i.Fields.Unknowns = ticket.CustomFields
+	}
+
+	// Create ticket
+	issue, response, err := t.createIssue(&i)
+
+	if err != nil {
+		log.Println(err)
+		if response != nil {
+			data, err2 := ioutil.ReadAll(response.Body)
+			if err2 != nil {
+				log.Println(err2)
+			}
+			log.Println(string(data))
+		}
+		return newJiraRequestError("create ticket", response, err)
+	}
+
+	ticket.Key = issue.Key
+	ticket.URL = t.getTicketURL(issue.Key)
+	return nil
+}
+
+// Cloud doesn't allow to search by email address directly, but the query matches it
+// Server returns the username, which is what is used for users there
+func (t *jiraTracker) FindUser(email string) (string, error) {
+	parameter := "query"
+	if t.isServer() {
+		parameter = "username"
+	}
+
+	req, err := t.client.NewRequest(http.MethodGet, "rest/api/2/user/search?"+parameter+"="+url.QueryEscape(email), nil)
+	if err != nil {
+		return "", &TrackerClientError{Tracker: "JIRA", Err: err}
+	}
+
+	users := []jira.User{}
+	if response, err := t.client.Do(req, &users); err != nil {
+		return "", newJiraRequestError("search users", response, err)
+	}
+
+	for _, user := range users {
+		if !user.Active {
+			continue
+		}
+		if t.isServer() {
+			return user.Name, nil
+		}
+		return user.AccountID, nil
+	}
+	return "", nil
+}
+
+// JIRA rejects tickets with unknown components or versions, so missing ones are created first if enabled
+// Failures are only logged, creating the ticket then tells whether the component or version is really missing
+func (t *jiraTracker) createMissingComponentsAndVersions(ticket *Ticket) {
+	project, response, err := t.client.Project.Get(ticket.Project)
+	if err != nil {
+		log.Println("[jira.go]", newJiraRequestError("get project "+ticket.Project, response, err))
+		return
+	}
+
+	if t.details.CreateComponents {
+		existing := map[string]bool{}
+		for _, component := range project.Components {
+			existing[strings.ToLower(component.Name)] = true
+		}
+		for _, component := range ticket.Components {
+			if existing[strings.ToLower(component)] {
+				continue
+			}
+			log.Println("[jira.go] Creating component " + component + " in project " + project.Key)
+			if _, response, err := t.client.Component.Create(&jira.CreateComponentOptions{Name: component, Project: project.Key}); err != nil {
+				log.Println("[jira.go]", newJiraRequestError("create component "+component, response, err))
+			}
+		}
+	}
+
+	if t.details.CreateVersions {
+		projectID, _ := strconv.Atoi(project.ID)
+		existing := map[string]bool{}
+		for _, version := range project.Versions {
+			existing[strings.ToLower(version.Name)] = true
+		}
+		for _, version := range append(append([]string{}, ticket.FixVersions...), ticket.AffectsVersions...) {
+			if existing[strings.ToLower(version)] {
+				continue
+			}
+			existing[strings.ToLower(version)] = true
+			log.Println("[jira.go] Creating version " + version + " in project " + project.Key)
+			// Version.Create of go-jira uses an absolute path, which breaks base URLs with a path like the OAuth 2.0 gateway
+			req, err := t.client.NewRequest(http.MethodPost, "rest/api/2/version", &jira.Version{Name: version, ProjectID: projectID})
+			if err != nil {
+				log.Println("[jira.go]", err)
+				continue
+			}
+			if response, err := t.client.Do(req, nil); err != nil {
+				log.Println("[jira.go]", newJiraRequestError("create version "+version, response, err))
+			}
+		}
+	}
+}
+
+func (t *jiraTracker) AddAttachment(key string, name string, content []byte) error {
+	if _, response, err := t.client.Issue.PostAttachment(key, bytes.NewReader(content), name); err != nil {
+		return newJiraRequestError("attach "+name+" to ticket "+key, response, err)
+	}
+	return nil
+}
+
+// Priorities are given by name or by their numeric ID
+func newJIRAPriority(priority string) *jira.Priority {
+	if _, err := strconv.Atoi(priority); err == nil {
+		return &jira.Priority{ID: priority}
+	}
+	return &jira.Priority{Name: priority}
+}
+
+// JIRA Cloud identifies users by account ID, JIRA Server and Data Center by username
+func (t *jiraTracker) newUser(user string) *jira.User {
+	if user == "" {
+		return nil
+	}
+	if t.isServer() {
+		return &jira.User{Name: user}
+	}
+	return &jira.User{AccountID: user}
+}
+
+func (t *jiraTracker) isServer() bool {
+	return strings.EqualFold(t.details.Deployment, jiraDeploymentServer)
+}
+
+// REST API v3 expects the description as ADF document instead of wiki markup
+func (t *jiraTracker) createIssue(issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	if !t.useAPIv3() {
+		return t.client.Issue.Create(issue)
+	}
+
+	fields := *issue.Fields
+	fields.Description = ""
+	fields.Unknowns = map[string]interface{}{}
+	for key, value := range issue.Fields.Unknowns {
+		fields.Unknowns[key] = value
+	}
+	fields.Unknowns["description"] = convertWikiMarkupToADF(issue.Fields.Description)
+
+	req, err := t.client.NewRequest(http.MethodPost, "rest/api/3/issue", &jira.Issue{Fields: &fields})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	createdIssue := &jira.Issue{}
+	response, err := t.client.Do(req, createdIssue)
+	if err != nil {
+		return nil, response, err
+	}
+	return createdIssue, response, nil
+}
+
+// JIRA Server and Data Center only have the REST API v2
+func (t *jiraTracker) useAPIv3() bool {
+	return t.details.APIVersion == "3" && !t.isServer()
+}
+
+func (t *jiraTracker) UpdateLabels(key string, add []string, remove []string) error {
+	operations := []map[string]string{}
+	for _, label := range remove {
+		operations = append(operations, map[string]string{"remove": label})
+	}
+	for _, label := range add {
+		operations = append(operations, map[string]string{"add": label})
+	}
+
+	update := map[string]interface{}{
+		"update": map[string]interface{}{
+			"labels": operations,
+		},
+	}
+	if response, err := t.client.Issue.UpdateIssue(key, update); err != nil {
+		return newJiraRequestError("update labels of ticket "+key, response, err)
+	}
+	return nil
+}
+
+func (t *jiraTracker) AddComment(key string, body string) error {
+	if !t.useAPIv3() {
+		if _, response, err := t.client.Issue.AddComment(key, &jira.Comment{Body: body}); err != nil {
+			return newJiraRequestError("add comment to ticket "+key, response, err)
+		}
+		return nil
+	}
+
+	req, err := t.client.NewRequest(http.MethodPost, "rest/api/3/issue/"+key+"/comment", map[string]interface{}{"body": convertWikiMarkupToADF(body)})
+	if err != nil {
+		return &TrackerClientError{Tracker: "JIRA", Err: err}
+	}
+	if response, err := t.client.Do(req, nil); err != nil {
+		return newJiraRequestError("add comment to ticket "+key, response, err)
+	}
+	return nil
+}
+
+func (t *jiraTracker) Transition(key string, transition string) error {
+	transitionID, err := t.findTransitionID(key, transition)
+	if err != nil {
+		return err
+	}
+
+	if response, err := t.client.Issue.DoTransition(key, transitionID); err != nil {
+		return newJiraRequestError("transition ticket "+key, response, err)
+	}
+	return nil
+}
+
+// Transitions are matched by their name or by the name of the status they lead to
+func (t *jiraTracker) findTransitionID(key string, transitionName string) (string, error) {
+	transitions, response, err := t.client.Issue.GetTransitions(key)
+	if err != nil {
+		return "", newJiraRequestError("get transitions of ticket "+key, response, err)
+	}
+
+	for _, transition := range transitions {
+		if strings.EqualFold(transition.Name, transitionName) || strings.EqualFold(transition.To.Name, transitionName) {
+			return transition.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("transition %s is not available for ticket %s", transitionName, key)
+}
+
+// Searches in all projects, as routing rules might have sent the ticket to another project than the default one
+// Tickets in a done status category are ignored
+func (t *jiraTracker) FindOpenTicket(label string) (*Ticket, error) {
+	jql := fmt.Sprintf("labels = \"%s\" AND statusCategory != Done ORDER BY created DESC", label)
+
+	issues, response, err := t.client.Issue.Search(jql, &jira.SearchOptions{MaxResults: 1})
+	if err != nil {
+		return nil, newJiraRequestError("search tickets", response, err)
+	}
+
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return t.newTicket(&issues[0]), nil
+}
+
+func (t *jiraTracker) GetTicket(key string) (*Ticket, error) {
+	issue, response, err := t.client.Issue.Get(key, &jira.GetQueryOptions{Fields: "summary,status,labels"})
+	if err != nil {
+		return nil, newJiraRequestError("get ticket "+key, response, err)
+	}
+	return t.newTicket(issue), nil
+}
+
+func (t *jiraTracker) newTicket(issue *jira.Issue) *Ticket {
+	ticket := &Ticket{
+		Key: issue.Key,
+		URL: t.getTicketURL(issue.Key),
+	}
+	if fields := issue.Fields; fields != nil {
+		ticket.Summary = fields.Summary
+		ticket.Labels = fields.Labels
+		if fields.Status != nil {
+			ticket.Status = fields.Status.Name
+		}
+	}
+	return ticket
+}
+
+func (t *jiraTracker) getTicketURL(key string) string {
+	return strings.TrimSuffix(t.details.SiteURL, "/") + "/browse/" + key
+}
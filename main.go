@@ -2,14 +2,19 @@ package main
 
 /*
  * Reacts to sh.keptn.event.evaluation.finished and sh.keptn.events.problem
+ * Executes the jira task (sh.keptn.event.jira.triggered)
+ * Opens approval tickets for sh.keptn.event.approval.triggered and receives JIRA webhooks to finish them
  */
 
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2" // make sure to use v2 cloudevents here
 	"github.com/kelseyhightower/envconfig"
@@ -29,10 +34,24 @@ type envConfig struct {
 	Env string `envconfig:"ENV" default:"local"`
 	// URL of the Keptn configuration service (this is where we can fetch files from the config repo)
 	ConfigurationServiceUrl string `envconfig:"CONFIGURATION_SERVICE" default:""`
+	// Path to which JIRA webhooks are sent (served on the same port as the cloudevents)
+	WebhookPath string `envconfig:"JIRA_WEBHOOK_PATH" default:"/jira/webhook"`
 }
 
 type JiraDetails struct {
-	BaseURL              string
+	// Tracker is jira (default) or github
+	Tracker string
+	GitHub  GitHubDetails
+	BaseURL string
+	// Deployment is cloud (default) or server, which also covers Data Center
+	Deployment string
+	// AuthType is basic (default), pat, oauth2 or oauth1
+	AuthType string
+	OAuth    OAuthDetails
+	// SiteURL is used for links to tickets, as OAuth 2.0 calls go to api.atlassian.com instead of the site
+	SiteURL string
+	// APIVersion is 2 (wiki markup, default) or 3 (Atlassian Document Format, JIRA Cloud only)
+	APIVersion           string
 	Username             string
 	APIToken             string
 	AssigneeID           string
@@ -41,6 +60,35 @@ type JiraDetails struct {
 	IssueType            string
 	TicketForProblems    bool
 	TicketForEvaluations bool
+	TicketForApprovals   bool
+	ResolveTransition    string
+	EscalateTransition   string
+	ApprovedStatus       string
+	RejectedStatus       string
+	Labels               []string
+	Templates            map[string]TicketTemplate
+	WebhookRules         []WebhookRule
+	Priorities           []PriorityRule
+	CustomFields         []CustomFieldMapping
+	Routes               []RoutingRule
+	// ServiceComponent sets the Keptn service as component, CreateComponents creates missing components in the project
+	ServiceComponent bool
+	CreateComponents bool
+	// VersionField is fixVersions, affectsVersions or empty to not set a version
+	VersionField string
+	// VersionLabel is the label of the event which holds the artifact version
+	VersionLabel   string
+	CreateVersions bool
+	// AttachPayloads uploads the CloudEvent, the evaluation and the SLO file to the ticket
+	AttachPayloads bool
+	// CommentTimeline adds the finished events of TimelineTasks as comments to the ticket of the Keptn context
+	CommentTimeline bool
+	TimelineTasks   []string
+	// TicketForTasks lists the tasks (deployment, test, release, rollback) which get a ticket if they finish with fail or warning
+	TicketForTasks []string
+	// TicketForAnyTask creates tickets for every other task which finishes with one of TicketForResults (result or status)
+	TicketForAnyTask bool
+	TicketForResults []string
 }
 
 type KeptnDetails struct {
@@ -57,35 +105,172 @@ const ServiceName = "jira-service"
 // This method gets called when a new event is received from the Keptn Event Distributor
 func processKeptnCloudEvent(ctx context.Context, event cloudevents.Event) error {
 
+	// Most events of the subscription are of no interest, they are dropped before jira.yaml is loaded
+	if !isJIRAEventType(event.Type()) {
+		return nil
+	}
+
+	// The Keptn Handler can't deal with events without a Keptn context
+	if _, ok := event.Extensions()["shkeptncontext"].(string); !ok {
+		log.Printf("[main.go] Event %s has no shkeptncontext", event.ID())
+		return &PayloadError{EventType: event.Type(), Err: errors.New("missing shkeptncontext extension")}
+	}
+
 	// create keptn handler
 	log.Printf("[main.go] Initializing Keptn Handler")
 	myKeptn, err := keptnv2.NewKeptn(&event, keptnOptions)
 	if err != nil {
-		return errors.New("Could not create Keptn Handler: " + err.Error())
+		log.Printf("[main.go] Could not create Keptn Handler for event %s: %s", event.ID(), err)
+		return &PayloadError{EventType: event.Type(), Err: err}
 	}
 
-	setupAndDebug(myKeptn, event)
+	// Events are processed concurrently, so every event gets its own copy of the settings
+	details := loadJIRADetails(myKeptn)
+
+	setupAndDebug(myKeptn, event, details)
 
 	log.Println("[main.go] Received Cloud Event Type: " + event.Type())
 
 	if event.Type() == "sh.keptn.events.problem" { // sh.keptn.events.problem
 		log.Println("Processing sh.keptn.events.problem Event")
 
-		eventData := &keptnv2.ActionFinishedEventData{}
-		parseKeptnCloudEventPayload(event, eventData)
+		eventData := &ProblemEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		if eventData.IsClosed() {
+			err = HandleProblemClosedEvent(myKeptn, event, eventData, details)
+		} else {
+			err = HandleProblemEvent(myKeptn, event, eventData, details)
+		}
+	}
+	if isRemediationEventType(event.Type(), "triggered") { // sh.keptn.event.<stage>.remediation.triggered
+		log.Println("Processing remediation.triggered Event")
+
+		eventData := &RemediationTriggeredEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleRemediationTriggeredEvent(myKeptn, event, eventData, details)
+	}
+	if isRemediationEventType(event.Type(), "status.changed") { // sh.keptn.event.remediation.status.changed
+		log.Println("Processing remediation.status.changed Event")
+
+		eventData := &RemediationStatusChangedEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleRemediationStatusChangedEvent(myKeptn, event, eventData, details)
+	}
+	if isRemediationEventType(event.Type(), "finished") { // sh.keptn.event.remediation.finished or sh.keptn.event.<stage>.remediation.finished
+		log.Println("Processing remediation.finished Event")
+
+		eventData := &keptnv2.EventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
 
-		HandleProblemEvent(myKeptn, event, eventData)
+		err = HandleRemediationFinishedEvent(myKeptn, event, eventData, details)
+	}
+	if event.Type() == keptnv2.GetTriggeredEventType(JiraTaskName) { // sh.keptn.event.jira.triggered
+		log.Println("Processing jira.triggered Event")
+
+		eventData := &JiraTriggeredEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleJiraTriggeredEvent(myKeptn, event, eventData, details)
+	}
+	if event.Type() == keptnv2.GetTriggeredEventType(keptnv2.ApprovalTaskName) { // sh.keptn.event.approval.triggered
+		log.Println("Processing approval.triggered Event")
+
+		eventData := &keptnv2.ApprovalTriggeredEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleApprovalTriggeredEvent(myKeptn, event, eventData, details)
 	}
 	if event.Type() == "sh.keptn.event.evaluation.finished" { // sh.keptn.event.evaluation.finished
 		log.Println("Processing evaluation.finished Event")
 
 		eventData := &keptnv2.EvaluationFinishedEventData{}
-		parseKeptnCloudEventPayload(event, eventData)
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
 
-		HandleEvaluationFinishedEvent(myKeptn, event, eventData)
+		err = HandleEvaluationFinishedEvent(myKeptn, event, eventData, details)
 	}
+	if event.Type() == keptnv2.GetFinishedEventType(keptnv2.DeploymentTaskName) { // sh.keptn.event.deployment.finished
+		log.Println("Processing deployment.finished Event")
 
-	return nil
+		eventData := &keptnv2.DeploymentFinishedEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleTaskFinishedEvent(myKeptn, event, keptnv2.DeploymentTaskName, &eventData.EventData, eventData, details)
+	}
+	if event.Type() == keptnv2.GetFinishedEventType(keptnv2.TestTaskName) { // sh.keptn.event.test.finished
+		log.Println("Processing test.finished Event")
+
+		eventData := &keptnv2.TestFinishedEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleTaskFinishedEvent(myKeptn, event, keptnv2.TestTaskName, &eventData.EventData, eventData, details)
+	}
+	if event.Type() == keptnv2.GetFinishedEventType(keptnv2.ReleaseTaskName) { // sh.keptn.event.release.finished
+		log.Println("Processing release.finished Event")
+
+		eventData := &keptnv2.ReleaseFinishedEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleTaskFinishedEvent(myKeptn, event, keptnv2.ReleaseTaskName, &eventData.EventData, eventData, details)
+	}
+	if event.Type() == keptnv2.GetFinishedEventType(keptnv2.RollbackTaskName) { // sh.keptn.event.rollback.finished
+		log.Println("Processing rollback.finished Event")
+
+		eventData := &keptnv2.RollbackFinishedEventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleTaskFinishedEvent(myKeptn, event, keptnv2.RollbackTaskName, &eventData.EventData, eventData, details)
+	}
+	if task, ok := getGenericTicketTask(event, details); ok { // sh.keptn.event.<task>.finished of any other task
+		log.Println("Processing " + event.Type() + " Event with the catch-all")
+
+		eventData := &keptnv2.EventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleGenericTaskFinishedEvent(myKeptn, event, task, eventData, details)
+	}
+	// Runs after the handlers above, which might have created or commented the ticket already
+	if task, ok := getTimelineTask(event, details); ok && err == nil { // sh.keptn.event.<task>.finished
+		log.Println("Processing " + event.Type() + " Event for the ticket timeline")
+
+		eventData := &keptnv2.EventData{}
+		if err := parseKeptnCloudEventPayload(event, eventData); err != nil {
+			return err
+		}
+
+		err = HandleTimelineEvent(myKeptn, event, task, eventData, details)
+	}
+
+	if err != nil {
+		log.Printf("[main.go] Could not process %s event %s: %s", event.Type(), event.ID(), err)
+	}
+	return err
 
 }
 
@@ -94,7 +279,7 @@ func processKeptnCloudEvent(ctx context.Context, event cloudevents.Event) error
  * no args: starts listening for cloudnative events on localhost:port/path
  *
  * Environment Variables
- * env=runlocal   -> will fetch resources from local drive instead of configuration service
+ * ENV=local      -> will fetch resources from local drive instead of configuration service (default)
  */
 func main() {
 	var env envConfig
@@ -117,6 +302,12 @@ func _main(args []string, env envConfig) int {
 
 	keptnOptions.ConfigurationServiceURL = env.ConfigurationServiceUrl
 
+	// The settings from the environment are read once, jira.yaml is applied on a copy for every event
+	// KEPTN_DOMAIN must be set but KEPTN_BRIDGE_URL is optional in jira-service deployment.yaml file
+	setJIRADetails()
+	setKeptnDetails()
+	startOutbox()
+
 	log.Printf("[main.go] Starting %s...", ServiceName)
 	log.Printf("[main.go]     on Port = %d; Path=%s", env.Port, env.Path)
 
@@ -126,18 +317,24 @@ func _main(args []string, env envConfig) int {
 	log.Printf("[main.go] Creating new http handler")
 
 	// configure http server to receive cloudevents
-	p, err := cloudevents.NewHTTP(cloudevents.WithPath(env.Path), cloudevents.WithPort(env.Port))
+	p, err := cloudevents.NewHTTP()
 
 	if err != nil {
 		log.Fatalf("[main.go] failed to create client, %v", err)
 	}
-	c, err := cloudevents.NewClient(p)
+	receiveHandler, err := cloudevents.NewHTTPReceiveHandler(ctx, p, processKeptnCloudEvent)
 	if err != nil {
-		log.Fatalf("failed to create client, %v", err)
+		log.Fatalf("failed to create handler, %v", err)
 	}
 
+	// JIRA webhooks are served next to the cloudevents
+	mux := http.NewServeMux()
+	mux.Handle(env.Path, receiveHandler)
+	mux.HandleFunc(env.WebhookPath, handleJIRAWebhook)
+
 	log.Printf("[main.go] Starting receiver")
-	log.Fatal(c.StartReceiver(ctx, processKeptnCloudEvent))
+	log.Printf("[main.go]     JIRA webhook on Path=%s", env.WebhookPath)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", env.Port), mux))
 
 	return 0
 }
@@ -148,14 +345,68 @@ func _main(args []string, env envConfig) int {
 func parseKeptnCloudEventPayload(event cloudevents.Event, data interface{}) error {
 	err := event.DataAs(data)
 	if err != nil {
-		log.Fatalf("Got Data Error: %s", err.Error())
-		return err
+		log.Printf("[main.go] Got Data Error for event %s: %s", event.ID(), err.Error())
+		return &PayloadError{EventType: event.Type(), Err: err}
 	}
 	return nil
 }
 
+// Returns whether the jira-service might create or comment a ticket for the event
+// Whether it actually does depends on the settings, which are only loaded for these events
+func isJIRAEventType(eventType string) bool {
+	switch {
+	case eventType == "sh.keptn.events.problem",
+		eventType == keptnv2.GetTriggeredEventType(JiraTaskName),
+		eventType == keptnv2.GetTriggeredEventType(keptnv2.ApprovalTaskName),
+		isRemediationEventType(eventType, "triggered"),
+		isRemediationEventType(eventType, "status.changed"),
+		isRemediationEventType(eventType, "finished"):
+		return true
+	}
+
+	// Finished tasks might get a ticket or a timeline comment
+	if !keptnv2.IsTaskEventType(eventType) {
+		return false
+	}
+	_, kind, _ := keptnv2.ParseTaskEventType(eventType)
+	return kind == "finished"
+}
+
+// Remediation events are task events (sh.keptn.event.remediation.<kind>) or, since Keptn 0.8,
+// events of the remediation sequence of a stage (sh.keptn.event.<stage>.remediation.<kind>)
+func isRemediationEventType(eventType string, kind string) bool {
+	if eventType == "sh.keptn.event.remediation."+kind {
+		return true
+	}
+	if strings.Contains(kind, ".") || !keptnv2.IsSequenceEventType(eventType) {
+		return false
+	}
+	_, sequence, sequenceKind, _ := keptnv2.ParseSequenceEventType(eventType)
+	return strings.HasPrefix(eventType, "sh.keptn.event.") && sequence == "remediation" && sequenceKind == kind
+}
+
 func setJIRADetails() {
+	JIRA_DETAILS.Tracker = os.Getenv("TRACKER")
+	setGitHubDetails()
+
 	JIRA_DETAILS.BaseURL = os.Getenv("JIRA_BASE_URL")
+	JIRA_DETAILS.SiteURL = os.Getenv("JIRA_SITE_URL")
+	if JIRA_DETAILS.SiteURL == "" {
+		JIRA_DETAILS.SiteURL = JIRA_DETAILS.BaseURL
+	}
+	JIRA_DETAILS.Deployment = os.Getenv("JIRA_DEPLOYMENT")
+	if JIRA_DETAILS.Deployment == "" {
+		JIRA_DETAILS.Deployment = jiraDeploymentCloud
+	}
+	JIRA_DETAILS.AuthType = os.Getenv("JIRA_AUTH_TYPE")
+	if JIRA_DETAILS.AuthType == "" {
+		JIRA_DETAILS.AuthType = jiraAuthBasic
+	}
+	setOAuthDetails()
+	JIRA_DETAILS.APIVersion = os.Getenv("JIRA_API_VERSION")
+	if JIRA_DETAILS.APIVersion == "" {
+		JIRA_DETAILS.APIVersion = "2"
+	}
 	JIRA_DETAILS.Username = os.Getenv("JIRA_USERNAME")
 	JIRA_DETAILS.AssigneeID = os.Getenv("JIRA_ASSIGNEE_ID")
 	JIRA_DETAILS.ReporterID = os.Getenv("JIRA_REPORTER_ID")
@@ -164,6 +415,83 @@ func setJIRADetails() {
 	JIRA_DETAILS.IssueType = os.Getenv("JIRA_ISSUE_TYPE")
 	JIRA_DETAILS.TicketForProblems, _ = strconv.ParseBool(os.Getenv("JIRA_TICKET_FOR_PROBLEMS"))
 	JIRA_DETAILS.TicketForEvaluations, _ = strconv.ParseBool(os.Getenv("JIRA_TICKET_FOR_EVALUATIONS"))
+	JIRA_DETAILS.TicketForApprovals, _ = strconv.ParseBool(os.Getenv("JIRA_TICKET_FOR_APPROVALS"))
+	JIRA_DETAILS.TicketForTasks = nil
+	if ticketForTasks := os.Getenv("JIRA_TICKET_FOR_TASKS"); ticketForTasks != "" {
+		JIRA_DETAILS.TicketForTasks = strings.Split(ticketForTasks, ",")
+	}
+	JIRA_DETAILS.TicketForAnyTask, _ = strconv.ParseBool(os.Getenv("JIRA_TICKET_FOR_ANY_TASK"))
+	JIRA_DETAILS.TicketForResults = defaultTicketForResults
+	if ticketForResults := os.Getenv("JIRA_TICKET_FOR_RESULTS"); ticketForResults != "" {
+		JIRA_DETAILS.TicketForResults = strings.Split(ticketForResults, ",")
+	}
+
+	// Components and versions release managers filter on
+	JIRA_DETAILS.ServiceComponent, _ = strconv.ParseBool(os.Getenv("JIRA_SERVICE_COMPONENT"))
+	JIRA_DETAILS.CreateComponents, _ = strconv.ParseBool(os.Getenv("JIRA_CREATE_COMPONENTS"))
+	JIRA_DETAILS.VersionField = os.Getenv("JIRA_VERSION_FIELD")
+	JIRA_DETAILS.VersionLabel = os.Getenv("JIRA_VERSION_LABEL")
+	if JIRA_DETAILS.VersionLabel == "" {
+		JIRA_DETAILS.VersionLabel = defaultVersionLabel
+	}
+	JIRA_DETAILS.CreateVersions, _ = strconv.ParseBool(os.Getenv("JIRA_CREATE_VERSIONS"))
+	JIRA_DETAILS.AttachPayloads, _ = strconv.ParseBool(os.Getenv("JIRA_ATTACH_PAYLOADS"))
+
+	// Workflow transition used to close tickets once Keptn reports the problem as gone
+	JIRA_DETAILS.ResolveTransition = os.Getenv("JIRA_RESOLVE_TRANSITION")
+	if JIRA_DETAILS.ResolveTransition == "" {
+		JIRA_DETAILS.ResolveTransition = "Done"
+	}
+
+	// Workflow transition used when the remediation failed, tickets are only labeled and commented if it is not set
+	JIRA_DETAILS.EscalateTransition = os.Getenv("JIRA_ESCALATE_TRANSITION")
+
+	// Progress of the sequence which is added to the ticket of the Keptn context
+	JIRA_DETAILS.CommentTimeline, _ = strconv.ParseBool(os.Getenv("JIRA_COMMENT_TIMELINE"))
+	JIRA_DETAILS.TimelineTasks = defaultTimelineTasks
+	if timelineTasks := os.Getenv("JIRA_TIMELINE_TASKS"); timelineTasks != "" {
+		JIRA_DETAILS.TimelineTasks = strings.Split(timelineTasks, ",")
+	}
+
+	// Status of approval tickets which finish the approval task
+	JIRA_DETAILS.ApprovedStatus = os.Getenv("JIRA_APPROVED_STATUS")
+	if JIRA_DETAILS.ApprovedStatus == "" {
+		JIRA_DETAILS.ApprovedStatus = "Approved"
+	}
+	JIRA_DETAILS.RejectedStatus = os.Getenv("JIRA_REJECTED_STATUS")
+	if JIRA_DETAILS.RejectedStatus == "" {
+		JIRA_DETAILS.RejectedStatus = "Rejected"
+	}
+
+	// Additional labels can only be set through jira.yaml
+	JIRA_DETAILS.Labels = nil
+
+	// Templates for summary and description, jira.yaml takes precedence over JIRA_TEMPLATE_DIR
+	JIRA_DETAILS.Templates = map[string]TicketTemplate{}
+	setTicketTemplatesFromDirectory(JIRA_DETAILS.Templates)
+
+	// Rules for JIRA webhooks can only be set through jira.yaml
+	JIRA_DETAILS.WebhookRules = nil
+
+	// Priorities can only be set through jira.yaml
+	JIRA_DETAILS.Priorities = nil
+
+	// Custom fields can only be set through jira.yaml
+	JIRA_DETAILS.CustomFields = nil
+
+	// Routing rules can only be set through jira.yaml
+	JIRA_DETAILS.Routes = nil
+}
+
+// GitHub Issues is used instead of JIRA if TRACKER is set to github
+func setGitHubDetails() {
+	JIRA_DETAILS.GitHub.APIURL = os.Getenv("GITHUB_API_URL")
+	if JIRA_DETAILS.GitHub.APIURL == "" {
+		JIRA_DETAILS.GitHub.APIURL = "https://api.github.com"
+	}
+	JIRA_DETAILS.GitHub.Token = os.Getenv("GITHUB_TOKEN")
+	JIRA_DETAILS.GitHub.Repository = os.Getenv("GITHUB_REPOSITORY")
+	JIRA_DETAILS.GitHub.Assignee = os.Getenv("GITHUB_ASSIGNEE")
 }
 
 func setKeptnDetails() {
@@ -177,7 +505,7 @@ func setKeptnDetails() {
 	}
 }
 
-func setupAndDebug(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event) {
+func setupAndDebug(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, details *JiraDetails) {
 	log.Printf("[main.go] gotEvent(%s): %s - %s", incomingEvent.Type(), myKeptn.KeptnContext, incomingEvent.Context.GetID())
 
 	// Get Debug Mode
@@ -185,35 +513,54 @@ func setupAndDebug(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event) {
 	DEBUG, _ := strconv.ParseBool(os.Getenv("DEBUG"))
 	log.Printf("[main.go] Debug Mode: %v \n", DEBUG)
 
-	// Set JIRA Details
-	setJIRADetails()
-
 	// Get Dynatrace Tenant
 	dynaTraceTenant := os.Getenv("DT_TENANT")
 
-	// KEPTN_DOMAIN must be set but KEPTN_BRIDGE_URL is optional in jira-service deployment.yaml file
-	setKeptnDetails()
-
-	if JIRA_DETAILS.BaseURL == "" ||
-		JIRA_DETAILS.Username == "" ||
-		JIRA_DETAILS.APIToken == "" ||
-		JIRA_DETAILS.ProjectKey == "" ||
-		JIRA_DETAILS.IssueType == "" ||
-		KEPTN_DETAILS.Domain == "" {
-		log.Println("[main.go] Missing mandatory input parameters JIRA_BASE_URL and / or JIRA_USERNAME and / or JIRA_API_TOKEN and / or JIRA_PROJECT_KEY and / or JIRA_ISSUE_TYPE and / or KEPTN_DOMAIN.")
+	// The mandatory parameters depend on the tracker
+	missing := getMissingTrackerSettings(details)
+	if KEPTN_DETAILS.Domain == "" {
+		missing = append(missing, "KEPTN_DOMAIN")
+	}
+	if len(missing) > 0 {
+		log.Println("[main.go] Missing mandatory input parameters " + strings.Join(missing, " and / or ") + ".")
 	}
 
 	if DEBUG {
 		log.Println("[main.go] --- Printing JIRA Input Details ---")
-		log.Printf("[main.go] Base URL: %s \n", JIRA_DETAILS.BaseURL)
-		log.Printf("[main.go] Username: %s \n", JIRA_DETAILS.Username)
-		log.Printf("[main.go] Assignee ID: %s \n", JIRA_DETAILS.AssigneeID)
-		log.Printf("[main.go] Reporter ID: %s \n", JIRA_DETAILS.ReporterID)
-		log.Printf("[main.go] API Token: %s \n", JIRA_DETAILS.APIToken)
-		log.Printf("[main.go] Project Key: %s \n", JIRA_DETAILS.ProjectKey)
-		log.Printf("[main.go] Issue Type: %s \n", JIRA_DETAILS.IssueType)
-		log.Printf("[main.go] Ticket For Problems: %v \n", JIRA_DETAILS.TicketForProblems)
-		log.Printf("[main.go] Ticket For Problems: %v \n", JIRA_DETAILS.TicketForEvaluations)
+		log.Printf("[main.go] Tracker: %s \n", details.Tracker)
+		log.Printf("[main.go] GitHub API URL: %s \n", details.GitHub.APIURL)
+		log.Printf("[main.go] GitHub Repository: %s \n", details.GitHub.Repository)
+		log.Printf("[main.go] GitHub Assignee: %s \n", details.GitHub.Assignee)
+		log.Printf("[main.go] Base URL: %s \n", details.BaseURL)
+		log.Printf("[main.go] Deployment: %s \n", details.Deployment)
+		log.Printf("[main.go] Site URL: %s \n", details.SiteURL)
+		log.Printf("[main.go] Auth Type: %s \n", details.AuthType)
+		log.Printf("[main.go] API Version: %s \n", details.APIVersion)
+		log.Printf("[main.go] Username: %s \n", details.Username)
+		log.Printf("[main.go] Assignee ID: %s \n", details.AssigneeID)
+		log.Printf("[main.go] Reporter ID: %s \n", details.ReporterID)
+		log.Printf("[main.go] API Token: %s \n", details.APIToken)
+		log.Printf("[main.go] Project Key: %s \n", details.ProjectKey)
+		log.Printf("[main.go] Issue Type: %s \n", details.IssueType)
+		log.Printf("[main.go] Ticket For Problems: %v \n", details.TicketForProblems)
+		log.Printf("[main.go] Ticket For Problems: %v \n", details.TicketForEvaluations)
+		log.Printf("[main.go] Ticket For Approvals: %v \n", details.TicketForApprovals)
+		log.Printf("[main.go] Ticket For Tasks: %v \n", details.TicketForTasks)
+		log.Printf("[main.go] Ticket For Any Task: %v \n", details.TicketForAnyTask)
+		log.Printf("[main.go] Ticket For Results: %v \n", details.TicketForResults)
+		log.Printf("[main.go] Resolve Transition: %s \n", details.ResolveTransition)
+		log.Printf("[main.go] Escalate Transition: %s \n", details.EscalateTransition)
+		log.Printf("[main.go] Comment Timeline: %v \n", details.CommentTimeline)
+		log.Printf("[main.go] Timeline Tasks: %v \n", details.TimelineTasks)
+		log.Printf("[main.go] Approved Status: %s \n", details.ApprovedStatus)
+		log.Printf("[main.go] Rejected Status: %s \n", details.RejectedStatus)
+		log.Printf("[main.go] Labels: %v \n", details.Labels)
+		log.Printf("[main.go] Service Component: %v \n", details.ServiceComponent)
+		log.Printf("[main.go] Create Components: %v \n", details.CreateComponents)
+		log.Printf("[main.go] Version Field: %s \n", details.VersionField)
+		log.Printf("[main.go] Version Label: %s \n", details.VersionLabel)
+		log.Printf("[main.go] Create Versions: %v \n", details.CreateVersions)
+		log.Printf("[main.go] Attach Payloads: %v \n", details.AttachPayloads)
 		log.Println("[main.go] --- End Printing JIRA Input Details ---")
 
 		log.Printf("[main.go] Dynatrace Tenant: %s \n", dynaTraceTenant)
@@ -223,13 +570,13 @@ func setupAndDebug(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event) {
 		// At this point, we have all mandatory input params. Proceed
 		log.Println("[main.go] Got all input variables. Proceeding...")
 
-		if JIRA_DETAILS.TicketForProblems {
+		if details.TicketForProblems {
 			log.Println("[main.go] Will create tickets for problems")
 		} else {
 			log.Println("[main.go] Will NOT create tickets for problems")
 		}
 
-		if JIRA_DETAILS.TicketForEvaluations {
+		if details.TicketForEvaluations {
 			log.Println("[main.go] Will create tickets for evaluations")
 		} else {
 			log.Println("[main.go] Will NOT create tickets for evaluations")
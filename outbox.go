@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of outbound calls which are retried through the outbox
+const (
+	outboxKindTicket         = "ticket"
+	outboxKindComment        = "comment"
+	outboxKindDynatraceEvent = "dynatrace-event"
+)
+
+const (
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+)
+
+// OutboxEntry is a failed outbound call, stored as JSON file in the outbox directory until it succeeds
+// or is older than the max age, in which case it is moved to the dead letter directory
+type OutboxEntry struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Tracker     string          `json:"tracker,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+}
+
+// OutboxComment is the payload of a comment which couldn't be added
+type OutboxComment struct {
+	Key  string `json:"key"`
+	Body string `json:"body"`
+}
+
+type outboxConfig struct {
+	Dir      string
+	MaxAge   time.Duration
+	Interval time.Duration
+}
+
+var OUTBOX outboxConfig
+var outboxMutex sync.Mutex
+
+// Reads the outbox settings and starts the worker which retries the entries in the background
+func startOutbox() {
+	OUTBOX.Dir = os.Getenv("OUTBOX_DIR")
+	if OUTBOX.Dir == "" {
+		OUTBOX.Dir = filepath.Join(os.TempDir(), "jira-service", "outbox")
+	}
+	OUTBOX.MaxAge = parseDurationOrDefault(os.Getenv("OUTBOX_MAX_AGE"), 24*time.Hour)
+	OUTBOX.Interval = parseDurationOrDefault(os.Getenv("OUTBOX_INTERVAL"), 10*time.Second)
+
+	if err := os.MkdirAll(filepath.Join(OUTBOX.Dir, "dead"), 0755); err != nil {
+		log.Println("[outbox.go] Could not create outbox directory, failed calls will not be retried:", err)
+		OUTBOX.Dir = ""
+		return
+	}
+
+	log.Printf("[outbox.go] Retrying failed calls from %s every %s for at most %s", OUTBOX.Dir, OUTBOX.Interval, OUTBOX.MaxAge)
+
+	go func() {
+		for {
+			processOutbox()
+			time.Sleep(OUTBOX.Interval)
+		}
+	}()
+}
+
+func parseDurationOrDefault(value string, defaultValue time.Duration) time.Duration {
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("[outbox.go] Invalid duration %s, using %s", value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// Stores a failed call in the outbox, so that it is retried later on
+// The tracker is only set for calls to the issue tracker
+func enqueueOutboxEntry(kind string, tracker string, payload interface{}, lastError error) {
+	if OUTBOX.Dir == "" {
+		log.Println("[outbox.go] Outbox is disabled, dropping failed " + kind)
+		return
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		log.Println("[outbox.go] Could not encode "+kind+" for the outbox:", err)
+		return
+	}
+
+	now := time.Now()
+	entry := &OutboxEntry{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Tracker:   tracker,
+		Payload:   payloadJSON,
+		Attempts:  1,
+		CreatedAt: now,
+		LastError: lastError.Error(),
+	}
+	entry.NextAttempt = now.Add(getOutboxBackoff(entry.Attempts))
+
+	if err := writeOutboxEntry(OUTBOX.Dir, entry); err != nil {
+		log.Println("[outbox.go] Could not write "+kind+" to the outbox:", err)
+		return
+	}
+	log.Println("[outbox.go] Added failed " + kind + " to the outbox, next attempt at " + entry.NextAttempt.Format(time.RFC3339))
+}
+
+// Exponential backoff with jitter: the delay doubles with every attempt and is randomized by up to 50%
+func getOutboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func processOutbox() {
+	outboxMutex.Lock()
+	defer outboxMutex.Unlock()
+
+	files, err := ioutil.ReadDir(OUTBOX.Dir)
+	if err != nil {
+		log.Println("[outbox.go] Could not read outbox:", err)
+		return
+	}
+
+	now := time.Now()
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(OUTBOX.Dir, file.Name())
+		entry, err := readOutboxEntry(path)
+		if err != nil {
+			log.Println("[outbox.go] Could not read outbox entry "+file.Name()+":", err)
+			continue
+		}
+
+		if now.Sub(entry.CreatedAt) > OUTBOX.MaxAge {
+			moveOutboxEntryToDeadLetters(path, entry)
+			continue
+		}
+
+		if now.Before(entry.NextAttempt) {
+			continue
+		}
+
+		retryable, err := retryOutboxEntry(entry)
+		if err == nil {
+			log.Println("[outbox.go] Retried " + entry.Kind + " " + entry.ID + " successfully")
+			os.Remove(path)
+			continue
+		}
+
+		entry.Attempts++
+		entry.LastError = err.Error()
+		if !retryable {
+			moveOutboxEntryToDeadLetters(path, entry)
+			continue
+		}
+		entry.NextAttempt = now.Add(getOutboxBackoff(entry.Attempts))
+		if err := writeOutboxEntry(OUTBOX.Dir, entry); err != nil {
+			log.Println("[outbox.go] Could not update outbox entry:", err)
+		}
+		log.Println("[outbox.go] Retry of " + entry.Kind + " " + entry.ID + " failed: " + err.Error())
+	}
+}
+
+// Keeps the entry with its attempts and last error in the dead letter directory, so that it can be inspected or retried by hand
+func moveOutboxEntryToDeadLetters(path string, entry *OutboxEntry) {
+	log.Println("[outbox.go] Giving up on " + entry.Kind + " " + entry.ID + " after " + fmt.Sprint(entry.Attempts) + " attempts: " + entry.LastError)
+	if err := writeOutboxEntry(filepath.Join(OUTBOX.Dir, "dead"), entry); err != nil {
+		log.Println("[outbox.go] Could not move outbox entry to dead letters:", err)
+		return
+	}
+	os.Remove(path)
+
+	// Nobody can approve a ticket that was never created, so the sequence would wait forever
+	if entry.Kind == outboxKindTicket {
+		ticket := &Ticket{}
+		if err := json.Unmarshal(entry.Payload, ticket); err == nil && hasJIRALabel(ticket.Labels, approvalPendingLabel) {
+			finishApprovalWithoutTicket(ticket, entry.LastError)
+		}
+	}
+}
+
+// Returns whether the call should be retried again if it failed
+func retryOutboxEntry(entry *OutboxEntry) (bool, error) {
+	switch entry.Kind {
+	case outboxKindTicket:
+		ticket := &Ticket{}
+		if err := json.Unmarshal(entry.Payload, ticket); err != nil {
+			return false, err
+		}
+		return retryTicket(entry.Tracker, ticket)
+	case outboxKindComment:
+		comment := &OutboxComment{}
+		if err := json.Unmarshal(entry.Payload, comment); err != nil {
+			return false, err
+		}
+		tracker, err := newTrackerByName(entry.Tracker)
+		if err != nil {
+			return false, err
+		}
+		err = tracker.AddComment(comment.Key, comment.Body)
+		return isRetryableTrackerError(err), err
+	case outboxKindDynatraceEvent:
+		dtInfoEvent := &DtInfoEvent{}
+		if err := json.Unmarshal(entry.Payload, dtInfoEvent); err != nil {
+			return false, err
+		}
+		return sendDynatraceEvent(dtInfoEvent)
+	}
+	return false, fmt.Errorf("unknown kind of outbox entry: %s", entry.Kind)
+}
+
+// Creates the ticket unless a ticket for the Keptn context was created in the meantime
+func retryTicket(trackerName string, ticket *Ticket) (bool, error) {
+	details := JIRA_DETAILS
+	details.Tracker = trackerName
+
+	// The routing rules of jira.yaml aren't known here, so GitHub searches the repository the ticket was routed to
+	if strings.EqualFold(trackerName, trackerGitHub) && ticket.Project != "" {
+		details.GitHub.Repository = ticket.Project
+	}
+
+	tracker, err := newTracker(&details)
+	if err != nil {
+		return false, err
+	}
+
+	if keptnContext := getJIRALabelValue(ticket.Labels, createJIRAContextLabel("")); keptnContext != "" {
+		existingTicket, err := tracker.FindOpenTicket(createJIRAContextLabel(keptnContext))
+		if err != nil {
+			return true, err
+		}
+		if existingTicket != nil {
+			log.Println("[outbox.go] Found open ticket for Keptn context", keptnContext, ":", existingTicket.Key)
+			if err := tracker.AddComment(existingTicket.Key, "h4. "+ticket.Summary+"\n"+ticket.Description); err != nil {
+				return isRetryableTrackerError(err), err
+			}
+			addTicketAttachments(tracker, existingTicket.Key, ticket.Attachments)
+			return false, nil
+		}
+	}
+
+	if err := tracker.CreateTicket(ticket); err != nil {
+		return isRetryableTrackerError(err), err
+	}
+	log.Println("[outbox.go] Created ticket successfully: ", ticket.Key)
+	addTicketAttachments(tracker, ticket.Key, ticket.Attachments)
+	return false, nil
+}
+
+// Rate limits and server errors are worth a retry, other client errors are not
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode == 429 || statusCode >= 500
+}
+
+func writeOutboxEntry(dir string, entry *OutboxEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that the worker never reads half written entries
+	tmpPath := filepath.Join(dir, entry.ID+".tmp")
+	if err := ioutil.WriteFile(tmpPath, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(dir, entry.ID+".json"))
+}
+
+func readOutboxEntry(path string) (*OutboxEntry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &OutboxEntry{}
+	if err := json.Unmarshal(content, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
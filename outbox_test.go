@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+func TestProcessOutboxMovesFailedEntriesToDeadLetters(t *testing.T) {
+	outbox := OUTBOX
+	defer func() { OUTBOX = outbox }()
+	OUTBOX = outboxConfig{Dir: t.TempDir(), MaxAge: time.Hour}
+	if err := os.MkdirAll(filepath.Join(OUTBOX.Dir, "dead"), 0755); err != nil {
+		t.Fatalf("could not create dead letter directory: %s", err)
+	}
+
+	createdAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	tests := []struct {
+		name         string
+		entry        *OutboxEntry
+		wantAttempts int
+		wantError    string
+	}{
+		{
+			name:         "entry that can't be retried is moved right away",
+			entry:        &OutboxEntry{ID: "unknown-kind", Kind: "unknown", Attempts: 1, CreatedAt: createdAt, LastError: "first error"},
+			wantAttempts: 2,
+			wantError:    "unknown kind of outbox entry: unknown",
+		},
+		{
+			name:         "entry older than the max age is moved without another attempt",
+			entry:        &OutboxEntry{ID: "too-old", Kind: outboxKindComment, Attempts: 5, CreatedAt: createdAt.Add(-2 * time.Hour), LastError: "last error"},
+			wantAttempts: 5,
+			wantError:    "last error",
+		},
+	}
+
+	for _, tt := range tests {
+		if err := writeOutboxEntry(OUTBOX.Dir, tt.entry); err != nil {
+			t.Fatalf("could not write outbox entry: %s", err)
+		}
+	}
+
+	processOutbox()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := os.Stat(filepath.Join(OUTBOX.Dir, tt.entry.ID+".json")); !os.IsNotExist(err) {
+				t.Errorf("entry is still in the outbox")
+			}
+
+			dead, err := readOutboxEntry(filepath.Join(OUTBOX.Dir, "dead", tt.entry.ID+".json"))
+			if err != nil {
+				t.Fatalf("entry is not in the dead letters: %s", err)
+			}
+			if !dead.CreatedAt.Equal(tt.entry.CreatedAt) {
+				t.Errorf("CreatedAt = %s, want %s", dead.CreatedAt, tt.entry.CreatedAt)
+			}
+			if dead.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", dead.Attempts, tt.wantAttempts)
+			}
+			if dead.LastError != tt.wantError {
+				t.Errorf("LastError = %q, want %q", dead.LastError, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestProcessOutboxFinishesDeadLetteredApprovals(t *testing.T) {
+	outbox := OUTBOX
+	options := keptnOptions
+	defer func() {
+		OUTBOX = outbox
+		keptnOptions = options
+	}()
+	OUTBOX = outboxConfig{Dir: t.TempDir(), MaxAge: time.Hour}
+	if err := os.MkdirAll(filepath.Join(OUTBOX.Dir, "dead"), 0755); err != nil {
+		t.Fatalf("could not create dead letter directory: %s", err)
+	}
+	sender := &keptnv2.TestSender{}
+	keptnOptions.EventSender = sender
+
+	tickets := map[string]*Ticket{
+		"approval": {Summary: "[APPROVAL] sockshop", Labels: []string{
+			"keptn_project:sockshop", "keptn_stage:production", "keptn_service:carts",
+			approvalPendingLabel, approvalContextLabelPrefix + "context-1", approvalTriggeredIDLabelPrefix + "triggered-1",
+		}},
+		"evaluation": {Summary: "Evaluation failed", Labels: []string{"keptn_project:sockshop", createJIRAContextLabel("context-2")}},
+	}
+	for id, ticket := range tickets {
+		payload, _ := json.Marshal(ticket)
+		// The unknown tracker makes the retry fail for good
+		entry := &OutboxEntry{ID: id, Kind: outboxKindTicket, Tracker: "unknown", Payload: payload, Attempts: 1, CreatedAt: time.Now()}
+		if err := writeOutboxEntry(OUTBOX.Dir, entry); err != nil {
+			t.Fatalf("could not write outbox entry: %s", err)
+		}
+	}
+
+	processOutbox()
+
+	if err := sender.AssertSentEventTypes([]string{keptnv2.GetFinishedEventType(keptnv2.ApprovalTaskName)}); err != nil {
+		t.Fatalf("unexpected events: %s", err)
+	}
+	event := sender.SentEvents[0]
+	if triggeredID, _ := event.Extensions()["triggeredid"].(string); triggeredID != "triggered-1" {
+		t.Errorf("triggeredid = %q, want %q", triggeredID, "triggered-1")
+	}
+	if keptnContext, _ := event.Extensions()["shkeptncontext"].(string); keptnContext != "context-1" {
+		t.Errorf("shkeptncontext = %q, want %q", keptnContext, "context-1")
+	}
+
+	data := &keptnv2.ApprovalFinishedEventData{}
+	if err := event.DataAs(data); err != nil {
+		t.Fatalf("could not decode approval.finished: %s", err)
+	}
+	if data.Status != keptnv2.StatusErrored || data.Result != keptnv2.ResultFailed {
+		t.Errorf("status = %s, result = %s, want errored and fail", data.Status, data.Result)
+	}
+	if data.Project != "sockshop" || data.Stage != "production" || data.Service != "carts" {
+		t.Errorf("event data = %s/%s/%s, want sockshop/production/carts", data.Project, data.Stage, data.Service)
+	}
+}
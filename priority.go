@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// PriorityRule sets the JIRA priority of tickets which match all of its conditions
+// Rules are configured in jira.yaml, the first matching rule wins
+type PriorityRule struct {
+	// Priority is the name (e.g. Highest) or the ID (e.g. 1) of the JIRA priority
+	Priority string `yaml:"priority"`
+	// Result matches the result of the event, e.g. fail or warning
+	Result string `yaml:"result"`
+	// Stage matches the Keptn stage, e.g. production
+	Stage string `yaml:"stage"`
+	// MinScore and MaxScore match evaluation scores with MinScore <= score < MaxScore
+	MinScore *float64 `yaml:"minScore"`
+	MaxScore *float64 `yaml:"maxScore"`
+	// ImpactLevel matches the impact level of problems, e.g. APPLICATION, SERVICES or INFRASTRUCTURE
+	ImpactLevel string `yaml:"impactLevel"`
+	// SeverityLevel matches the severity level of problems, e.g. AVAILABILITY, ERROR or PERFORMANCE
+	SeverityLevel string `yaml:"severityLevel"`
+}
+
+// PriorityInput is what priority rules are matched against
+// Score is nil for events without evaluation, impact and severity level are empty for events other than problems
+type PriorityInput struct {
+	Result        string
+	Stage         string
+	Score         *float64
+	ImpactLevel   string
+	SeverityLevel string
+}
+
+// Returns the priority of the first matching rule, or an empty string to use the default priority of the JIRA project
+func getJIRAPriority(input *PriorityInput, details *JiraDetails) string {
+	for _, rule := range details.Priorities {
+		if rule.matches(input) {
+			log.Println("[priority.go] Using priority " + rule.Priority)
+			return rule.Priority
+		}
+	}
+	return ""
+}
+
+func (r *PriorityRule) matches(input *PriorityInput) bool {
+	if r.Result != "" && !strings.EqualFold(r.Result, input.Result) {
+		return false
+	}
+	if r.Stage != "" && !strings.EqualFold(r.Stage, input.Stage) {
+		return false
+	}
+	if r.MinScore != nil && (input.Score == nil || *input.Score < *r.MinScore) {
+		return false
+	}
+	if r.MaxScore != nil && (input.Score == nil || *input.Score >= *r.MaxScore) {
+		return false
+	}
+	if r.ImpactLevel != "" && !strings.EqualFold(r.ImpactLevel, input.ImpactLevel) {
+		return false
+	}
+	if r.SeverityLevel != "" && !strings.EqualFold(r.SeverityLevel, input.SeverityLevel) {
+		return false
+	}
+	return true
+}
+
+func newPriorityInputForEvaluation(data *keptnv2.EvaluationFinishedEventData) *PriorityInput {
+	score := data.Evaluation.Score
+	return &PriorityInput{
+		Result: string(data.Result),
+		Stage:  data.GetStage(),
+		Score:  &score,
+	}
+}
+
+// Dynatrace sends the impact and severity level with the problem details, other tools next to them
+func newPriorityInputForProblem(data *ProblemEventData) *PriorityInput {
+	return &PriorityInput{
+		Result:        string(data.Result),
+		Stage:         data.GetStage(),
+		ImpactLevel:   data.GetImpactLevel(),
+		SeverityLevel: data.GetSeverityLevel(),
+	}
+}
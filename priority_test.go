@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetJIRAPriority(t *testing.T) {
+	// The unquoted priority ID is a number in YAML and has to end up as the string JIRA expects
+	config := parseJIRAConfig([]byte(`
+priorities:
+  - priority: Highest
+    result: fail
+    stage: production
+  - priority: 2
+    maxScore: 50
+  - priority: Medium
+    minScore: 50
+    maxScore: 75.5
+  - priority: Low
+    impactLevel: application
+  - priority: Lowest
+    severityLevel: PERFORMANCE
+`), "project")
+	if config == nil {
+		t.Fatalf("could not parse priorities")
+	}
+	details := &JiraDetails{}
+	config.apply(details)
+
+	score := func(score float64) *float64 { return &score }
+	tests := []struct {
+		name  string
+		input *PriorityInput
+		want  string
+	}{
+		{
+			name:  "result and stage are case insensitive",
+			input: &PriorityInput{Result: "FAIL", Stage: "Production", Score: score(10)},
+			want:  "Highest",
+		},
+		{
+			name:  "priority ID",
+			input: &PriorityInput{Result: "fail", Stage: "staging", Score: score(49.9)},
+			want:  "2",
+		},
+		{
+			name:  "min score is inclusive",
+			input: &PriorityInput{Result: "warning", Score: score(50)},
+			want:  "Medium",
+		},
+		{
+			name:  "max score is exclusive",
+			input: &PriorityInput{Result: "warning", Score: score(75.5)},
+			want:  "",
+		},
+		{
+			name:  "score rules don't match events without score",
+			input: &PriorityInput{Result: "fail", Stage: "staging"},
+			want:  "",
+		},
+		{
+			name:  "impact level of a problem",
+			input: &PriorityInput{ImpactLevel: "APPLICATION", SeverityLevel: "PERFORMANCE"},
+			want:  "Low",
+		},
+		{
+			name:  "severity level of a problem",
+			input: &PriorityInput{ImpactLevel: "SERVICES", SeverityLevel: "performance"},
+			want:  "Lowest",
+		},
+		{
+			name:  "no matching rule",
+			input: &PriorityInput{ImpactLevel: "INFRASTRUCTURE", SeverityLevel: "AVAILABILITY"},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getJIRAPriority(tt.input, details); got != tt.want {
+				t.Errorf("getJIRAPriority(%+v) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPriorityInputForProblem(t *testing.T) {
+	tests := []struct {
+		name              string
+		event             string
+		wantImpactLevel   string
+		wantSeverityLevel string
+	}{
+		{
+			name:              "levels of the problem details win",
+			event:             `{"ProblemDetails": {"impactLevel": "SERVICE", "severityLevel": "ERROR"}, "ImpactLevel": "INFRASTRUCTURE"}`,
+			wantImpactLevel:   "SERVICE",
+			wantSeverityLevel: "ERROR",
+		},
+		{
+			name:              "levels next to plain text details",
+			event:             `{"ProblemDetails": "Response time degraded", "ImpactLevel": "APPLICATION", "SeverityLevel": "PERFORMANCE"}`,
+			wantImpactLevel:   "APPLICATION",
+			wantSeverityLevel: "PERFORMANCE",
+		},
+		{
+			name:  "problem without levels",
+			event: `{"ProblemTitle": "Response time degraded"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &ProblemEventData{}
+			if err := json.Unmarshal([]byte(tt.event), data); err != nil {
+				t.Fatalf("could not decode problem: %s", err)
+			}
+
+			input := newPriorityInputForProblem(data)
+			if input.ImpactLevel != tt.wantImpactLevel || input.SeverityLevel != tt.wantSeverityLevel {
+				t.Errorf("impact and severity level = %q, %q, want %q, %q", input.ImpactLevel, input.SeverityLevel, tt.wantImpactLevel, tt.wantSeverityLevel)
+			}
+			if input.Score != nil {
+				t.Errorf("Score = %v, want nil for problems", *input.Score)
+			}
+		})
+	}
+}
+
+func TestNewJIRAPriority(t *testing.T) {
+	tests := []struct {
+		priority string
+		wantID   string
+		wantName string
+	}{
+		{priority: "2", wantID: "2"},
+		{priority: "High", wantName: "High"},
+		{priority: "P1", wantName: "P1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priority, func(t *testing.T) {
+			got := newJIRAPriority(tt.priority)
+			if got.ID != tt.wantID || got.Name != tt.wantName {
+				t.Errorf("newJIRAPriority(%q) = ID %q, name %q, want ID %q, name %q", tt.priority, got.ID, got.Name, tt.wantID, tt.wantName)
+			}
+		})
+	}
+}
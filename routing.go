@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+)
+
+// RoutingRule sends tickets for matching events to another JIRA project, issue type or assignee
+// Rules are configured in jira.yaml, the first matching rule wins. If no rule matches, the defaults of the jira-service are used
+type RoutingRule struct {
+	Match RoutingMatch `yaml:"match"`
+
+	ProjectKey string `yaml:"projectKey"`
+	IssueType  string `yaml:"issueType"`
+	AssigneeID string `yaml:"assigneeId"`
+	// Components and Labels are added to the ticket
+	Components []string `yaml:"components"`
+	Labels     []string `yaml:"labels"`
+}
+
+// RoutingMatch holds the conditions of a routing rule, all conditions which are set have to match
+type RoutingMatch struct {
+	// EventType matches the type of the event, * can be used as wildcard, e.g. sh.keptn.event.*.finished
+	EventType string `yaml:"eventType"`
+	Project   string `yaml:"project"`
+	Stage     string `yaml:"stage"`
+	Service   string `yaml:"service"`
+	Result    string `yaml:"result"`
+	// Labels match the labels of the event, e.g. owner: team-a
+	Labels map[string]string `yaml:"labels"`
+}
+
+// Applies the first matching routing rule to the ticket
+func routeTicket(ticket *Ticket, templateData *TicketTemplateData, details *JiraDetails) {
+	for i, rule := range details.Routes {
+		if !rule.Match.matches(templateData) {
+			continue
+		}
+
+		log.Printf("[routing.go] Routing ticket with rule %d", i+1)
+		if rule.ProjectKey != "" {
+			ticket.Project = rule.ProjectKey
+		}
+		if rule.IssueType != "" {
+			ticket.IssueType = rule.IssueType
+		}
+		if rule.AssigneeID != "" {
+			ticket.Assignee = rule.AssigneeID
+		}
+		ticket.Components = append(ticket.Components, rule.Components...)
+		ticket.Labels = append(ticket.Labels, rule.Labels...)
+		return
+	}
+}
+
+func (m *RoutingMatch) matches(templateData *TicketTemplateData) bool {
+	if m.EventType != "" {
+		if matched, err := path.Match(m.EventType, templateData.EventType); err != nil || !matched {
+			return false
+		}
+	}
+	if m.Project != "" && !strings.EqualFold(m.Project, templateData.Project) {
+		return false
+	}
+	if m.Stage != "" && !strings.EqualFold(m.Stage, templateData.Stage) {
+		return false
+	}
+	if m.Service != "" && !strings.EqualFold(m.Service, templateData.Service) {
+		return false
+	}
+	if m.Result != "" && !strings.EqualFold(m.Result, fmt.Sprint(templateData.Event["result"])) {
+		return false
+	}
+
+	labels, _ := templateData.Event["labels"].(map[string]interface{})
+	for key, value := range m.Labels {
+		if label, ok := labels[key]; !ok || fmt.Sprint(label) != value {
+			return false
+		}
+	}
+	return true
+}
@@ -1,5 +1,164 @@
 package main
 
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// JiraTaskName is the name of the Keptn task which is executed by the jira-service
+const JiraTaskName = "jira"
+
+// JiraTriggeredEventData is the data of a sh.keptn.event.jira.triggered event
+type JiraTriggeredEventData struct {
+	keptnv2.EventData
+	Jira JiraTaskData `json:"jira"`
+}
+
+// JiraFinishedEventData is the data of the sh.keptn.event.jira.finished event sent by the jira-service
+type JiraFinishedEventData struct {
+	keptnv2.EventData
+	Jira JiraTaskData `json:"jira"`
+}
+
+// JiraTaskData holds the ticket details of the jira task
+// Summary and Description can be set in the triggered event to override the templates,
+// IssueKey and IssueURL are set in the finished event
+type JiraTaskData struct {
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	IssueKey    string `json:"issueKey,omitempty"`
+	IssueURL    string `json:"issueURL,omitempty"`
+}
+
+// ProblemEventData is the data of a sh.keptn.events.problem event, as sent by monitoring tools like Dynatrace or Prometheus
+// Project, stage, service and labels come with the embedded EventData
+type ProblemEventData struct {
+	keptnv2.EventData
+	ProblemData
+}
+
+// RemediationTriggeredEventData is the data of a sh.keptn.event.<stage>.remediation.triggered event,
+// which starts the remediation sequence of Keptn 0.8+ instead of the problem event
+type RemediationTriggeredEventData struct {
+	keptnv2.EventData
+	Problem ProblemData `json:"problem"`
+}
+
+// RemediationStatusChangedEventData is the data of a sh.keptn.event.remediation.status.changed event,
+// which is sent for every remediation action that is attempted
+type RemediationStatusChangedEventData struct {
+	keptnv2.EventData
+	Remediation RemediationStatus `json:"remediation"`
+}
+
+// RemediationStatus describes the remediation action that is attempted
+type RemediationStatus struct {
+	ActionIndex int    `json:"actionIndex"`
+	Result      string `json:"result,omitempty"`
+}
+
+// ProblemData holds the details of a problem, as sent with problem events and remediation.triggered events
+type ProblemData struct {
+	// State is OPEN, or CLOSED / RESOLVED once the problem is gone
+	State string `json:"State,omitempty"`
+	// ProblemID identifies the problem in the monitoring tool, PID is the ID which is shown to users
+	ProblemID      string         `json:"ProblemID"`
+	PID            string         `json:"PID"`
+	ProblemTitle   string         `json:"ProblemTitle"`
+	ProblemDetails ProblemDetails `json:"ProblemDetails"`
+	// ProblemURL links to the problem in the monitoring tool
+	ProblemURL     string `json:"ProblemURL,omitempty"`
+	ImpactedEntity string `json:"ImpactedEntity,omitempty"`
+	// Tags is a comma separated list of the tags of the impacted entities
+	Tags string `json:"Tags,omitempty"`
+	// ImpactLevel and SeverityLevel are sent by some tools next to the problem details
+	ImpactLevel   string `json:"ImpactLevel,omitempty"`
+	SeverityLevel string `json:"SeverityLevel,omitempty"`
+}
+
+// IsClosed returns true if the problem has been closed / resolved in the monitoring tool
+func (p *ProblemData) IsClosed() bool {
+	state := strings.ToUpper(p.State)
+	return state == "CLOSED" || state == "RESOLVED"
+}
+
+// GetImpactLevel prefers the impact level of the problem details
+func (p *ProblemData) GetImpactLevel() string {
+	if p.ProblemDetails.ImpactLevel != "" {
+		return p.ProblemDetails.ImpactLevel
+	}
+	return p.ImpactLevel
+}
+
+// GetSeverityLevel prefers the severity level of the problem details
+func (p *ProblemData) GetSeverityLevel() string {
+	if p.ProblemDetails.SeverityLevel != "" {
+		return p.ProblemDetails.SeverityLevel
+	}
+	return p.SeverityLevel
+}
+
+// ProblemDetails holds the details of a problem, including the root cause
+// Dynatrace sends an object, other tools like Prometheus send the details as plain text, which ends up in Description
+type ProblemDetails struct {
+	DisplayName   string                `json:"displayName,omitempty"`
+	Status        string                `json:"status,omitempty"`
+	ImpactLevel   string                `json:"impactLevel,omitempty"`
+	SeverityLevel string                `json:"severityLevel,omitempty"`
+	HasRootCause  bool                  `json:"hasRootCause,omitempty"`
+	RankedEvents  []ProblemRankedEvent  `json:"rankedEvents,omitempty"`
+	RankedImpacts []ProblemRankedImpact `json:"rankedImpacts,omitempty"`
+	Description   string                `json:"-"`
+}
+
+// ProblemRankedEvent is an event which contributes to the problem, ordered by relevance
+type ProblemRankedEvent struct {
+	EventType     string `json:"eventType"`
+	EntityName    string `json:"entityName"`
+	SeverityLevel string `json:"severityLevel,omitempty"`
+	ImpactLevel   string `json:"impactLevel,omitempty"`
+	IsRootCause   bool   `json:"isRootCause,omitempty"`
+}
+
+// ProblemRankedImpact is an entity which is impacted by the problem, ordered by relevance
+type ProblemRankedImpact struct {
+	EntityName    string `json:"entityName"`
+	EventType     string `json:"eventType"`
+	SeverityLevel string `json:"severityLevel,omitempty"`
+	ImpactLevel   string `json:"impactLevel,omitempty"`
+}
+
+// UnmarshalJSON accepts the details as object or as plain text
+// Details that don't match the expected structure are ignored, as they must not fail the whole event
+func (d *ProblemDetails) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &d.Description); err == nil {
+		return nil
+	}
+
+	type problemDetails ProblemDetails
+	details := problemDetails{}
+	if err := json.Unmarshal(data, &details); err != nil {
+		log.Println("[structs.go] Could not decode problem details:", err)
+		return nil
+	}
+	*d = ProblemDetails(details)
+	return nil
+}
+
+// GetRootCause returns the event which is the root cause of the problem, or nil if it is unknown
+func (d *ProblemDetails) GetRootCause() *ProblemRankedEvent {
+	for i := range d.RankedEvents {
+		if d.RankedEvents[i].IsRootCause {
+			return &d.RankedEvents[i]
+		}
+	}
+	return nil
+}
+
 type DtInfoEvent struct {
 	EventType string `json:"eventType"`
 	Source    string `json:"source"`
@@ -27,3 +186,17 @@ type DtTagRule struct {
 	MeTypes []string `json:"meTypes"`
 	Tags    []DtTag  `json:"tags"`
 }
+
+// JiraWebhookEvent is the payload JIRA sends to the webhook of the jira-service
+type JiraWebhookEvent struct {
+	WebhookEvent string                `json:"webhookEvent"`
+	User         *jira.User            `json:"user,omitempty"`
+	Issue        *jira.Issue           `json:"issue,omitempty"`
+	Comment      *jira.Comment         `json:"comment,omitempty"`
+	Changelog    *JiraWebhookChangelog `json:"changelog,omitempty"`
+}
+
+// JiraWebhookChangelog contains the fields that were changed with an issue_updated event
+type JiraWebhookChangelog struct {
+	Items []jira.ChangelogItems `json:"items"`
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2" // make sure to use v2 cloudevents here
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// Creates a ticket for deployment, test, release and rollback tasks which finished with fail or warning
+// data is the typed finished event (e.g. DeploymentFinishedEventData), which adds the task specific details
+func HandleTaskFinishedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, task string, eventData *keptnv2.EventData, data interface{}, details *JiraDetails) error {
+	log.Printf("[tasks.go] Handling %s.finished event: %s", task, incomingEvent.Context.GetID())
+
+	if !isTicketForTask(task, details) {
+		log.Println("[tasks.go] Task " + task + " is not part of TicketForTasks. Got a " + task + ".finished from Keptn but doing nothing. If you want a ticket, add it")
+		return nil
+	}
+
+	if !isTicketForTaskResult(eventData.Result) {
+		log.Println("[tasks.go] Task " + task + " finished with result " + string(eventData.Result) + ". No ticket needed")
+		return nil
+	}
+
+	_, err := createJIRATicketForTask(myKeptn, incomingEvent, task, eventData, data, details)
+	if isTicketQueuedError(err) {
+		log.Println("[tasks.go] Ticket for "+task+".finished will be created later on:", err)
+		return nil
+	}
+	return err
+}
+
+func isTicketForTask(task string, details *JiraDetails) bool {
+	for _, ticketTask := range details.TicketForTasks {
+		if strings.EqualFold(strings.TrimSpace(ticketTask), task) {
+			return true
+		}
+	}
+	return false
+}
+
+// Only tasks which didn't pass need a ticket
+func isTicketForTaskResult(result keptnv2.ResultType) bool {
+	return result == keptnv2.ResultFailed || result == keptnv2.ResultWarning
+}
+
+/********************************************
+*   CATCH-ALL FOR ANY OTHER TASK
+*********************************************/
+
+// Template kind of tickets for tasks without a template of their own
+const genericTaskTemplateKind = "task"
+
+// Results which create tickets for any task, unless JIRA_TICKET_FOR_RESULTS is set
+var defaultTicketForResults = []string{string(keptnv2.ResultFailed)}
+
+// Tasks which have handlers of their own
+var dedicatedTicketTasks = []string{keptnv2.EvaluationTaskName, keptnv2.ApprovalTaskName, JiraTaskName, "remediation"}
+
+// Returns the task of a sh.keptn.event.<task>.finished event, if the catch-all (TicketForAnyTask) is responsible for it
+func getGenericTicketTask(event cloudevents.Event, details *JiraDetails) (string, bool) {
+	if !details.TicketForAnyTask || !keptnv2.IsTaskEventType(event.Type()) {
+		return "", false
+	}
+
+	// Never react to our own events, a failed jira task would create tickets in a loop otherwise
+	if event.Source() == ServiceName {
+		return "", false
+	}
+
+	task, kind, _ := keptnv2.ParseTaskEventType(event.Type())
+	if kind != "finished" || isTicketForTask(task, details) {
+		return "", false
+	}
+	for _, dedicatedTask := range dedicatedTicketTasks {
+		if task == dedicatedTask {
+			return "", false
+		}
+	}
+	return task, true
+}
+
+// The configured results are matched against result and status, e.g. fail or errored
+func isTicketForAnyTaskResult(data *keptnv2.EventData, details *JiraDetails) bool {
+	for _, result := range details.TicketForResults {
+		result = strings.TrimSpace(result)
+		if strings.EqualFold(result, string(data.Result)) || strings.EqualFold(result, string(data.Status)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Creates a ticket for any other task, e.g. custom tasks like security scans, from the common EventData fields
+func HandleGenericTaskFinishedEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, task string, data *keptnv2.EventData, details *JiraDetails) error {
+	log.Printf("[tasks.go] Handling %s.finished event with the catch-all: %s", task, incomingEvent.Context.GetID())
+
+	if !isTicketForAnyTaskResult(data, details) {
+		log.Println("[tasks.go] Task " + task + " finished with result " + string(data.Result) + " and status " + string(data.Status) + ". No ticket needed")
+		return nil
+	}
+
+	_, err := createJIRATicketForTask(myKeptn, incomingEvent, task, data, data, details)
+	if isTicketQueuedError(err) {
+		log.Println("[tasks.go] Ticket for "+task+".finished will be created later on:", err)
+		return nil
+	}
+	return err
+}
+
+func createJIRATicketForTask(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, task string, eventData *keptnv2.EventData, data interface{}, details *JiraDetails) (*Ticket, error) {
+
+	log.Println("[tasks.go] Creating JIRA Body details for " + task + ".finished...")
+
+	// Build summary field (JIRA ticket title)
+	summary := "[" + strings.ToUpper(task) + "] " + eventData.GetProject() + " - " + eventData.GetService() + " - " + eventData.GetStage() + " - Result: " + string(eventData.Result)
+
+	description := "||*Task*||*Result*||*Project*||*Service*||*Stage*||\n"
+	description += "|" + task + "|" + getResultWithIcon(string(eventData.Result)) + "|" + eventData.GetProject() + "|" + eventData.GetService() + "|" + eventData.GetStage() + "|\n\n"
+
+	// Add what the task did
+	description += createDetailsForTask(data)
+
+	// Add Message
+	if eventData.Message != "" {
+		description += "Message: " + eventData.Message + "\n\n"
+	}
+
+	description += "Finished: " + formatEventTime(incomingEvent) + "\n"
+
+	// Add Keptn Context
+	description += "Keptn Context ID: " + myKeptn.KeptnContext + "\n"
+
+	// Add link to Keptn Bridge
+	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + eventData.GetProject() + "/sequence/" + myKeptn.KeptnContext
+	description += "[Link To Keptn's Bridge|" + bridgeURL + "]"
+
+	// Apply user supplied templates, if any. The task template covers all tasks without a template of their own
+	kind := task
+	if _, ok := details.Templates[task]; !ok {
+		kind = genericTaskTemplateKind
+	}
+	templateData := newTicketTemplateData(myKeptn, data, summary, description)
+	summary, description = renderTicketTemplate(kind, templateData, details)
+
+	ticket := &Ticket{
+		Summary:      summary,
+		Description:  description,
+		Labels:       createJIRALabelsForEventData(eventData),
+		CustomFields: getJIRACustomFields(templateData, details),
+		Priority:     getJIRAPriority(&PriorityInput{Result: string(eventData.Result), Stage: eventData.GetStage()}, details),
+		Attachments:  newEventAttachments(myKeptn, details),
+	}
+
+	setTicketComponentsAndVersions(ticket, templateData, details)
+	routeTicket(ticket, templateData, details)
+	resolveTicketAssignee(ticket, templateData, details)
+
+	// Send the POST to JIRA
+	return createJIRATicket(myKeptn.KeptnContext, ticket, details)
+}
+
+// Builds the task specific part of the description, e.g. the deployment strategy and URIs or the test start and end time
+func createDetailsForTask(data interface{}) string {
+	details := ""
+
+	switch d := data.(type) {
+	case *keptnv2.DeploymentFinishedEventData:
+		details += "Deployment Strategy: " + d.Deployment.DeploymentStrategy + "\n"
+		if len(d.Deployment.DeploymentNames) > 0 {
+			details += "Deployments: " + strings.Join(d.Deployment.DeploymentNames, ", ") + "\n"
+		}
+		for _, uri := range d.Deployment.DeploymentURIsPublic {
+			details += "Public URI: [" + uri + "|" + uri + "]\n"
+		}
+		for _, uri := range d.Deployment.DeploymentURIsLocal {
+			details += "Local URI: " + uri + "\n"
+		}
+		details += createGitCommitDetails(d.Deployment.GitCommit)
+	case *keptnv2.TestFinishedEventData:
+		details += "Test Start: " + d.Test.Start + "\n"
+		details += "Test End: " + d.Test.End + "\n"
+		start, startErr := time.Parse(time.RFC3339, d.Test.Start)
+		end, endErr := time.Parse(time.RFC3339, d.Test.End)
+		if startErr == nil && endErr == nil {
+			details += "Duration: " + end.Sub(start).String() + "\n"
+		}
+		details += createGitCommitDetails(d.Test.GitCommit)
+	case *keptnv2.ReleaseFinishedEventData:
+		details += createGitCommitDetails(d.Release.GitCommit)
+	}
+
+	if details != "" {
+		details += "\n"
+	}
+	return details
+}
+
+func createGitCommitDetails(gitCommit string) string {
+	if gitCommit == "" {
+		return ""
+	}
+	return "Git Commit: " + gitCommit + "\n"
+}
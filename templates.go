@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// TicketTemplate holds user supplied Go text/templates for the summary and description of a ticket
+// An empty template means the built-in text is used
+type TicketTemplate struct {
+	Summary     string `yaml:"summary"`
+	Description string `yaml:"description"`
+}
+
+// TicketTemplateData is what the ticket templates are rendered against
+type TicketTemplateData struct {
+	// KeptnContext is the shkeptncontext of the incoming event
+	KeptnContext string
+	// BridgeURL links to the sequence in Keptn's Bridge
+	BridgeURL string
+	// EventType is the CloudEvent type of the incoming event
+	EventType string
+	Project   string
+	Stage     string
+	Service   string
+	// Data is the typed data of the incoming event (e.g. EvaluationFinishedEventData)
+	Data interface{}
+	// Event is the untyped data of the incoming event, which allows to access fields the typed data doesn't know
+	Event map[string]interface{}
+	// Summary and Description hold the built-in texts, so templates can extend instead of replace them
+	Summary     string
+	Description string
+}
+
+// Functions take interface{} where possible, as e.g. the result is a ResultType and not a string
+var ticketTemplateFuncs = template.FuncMap{
+	"resultIcon": func(result interface{}) string { return getResultWithIcon(fmt.Sprint(result)) },
+	"sliTable":   createSLITableForEvaluationFinished,
+	"upper":      func(value interface{}) string { return strings.ToUpper(fmt.Sprint(value)) },
+	"lower":      func(value interface{}) string { return strings.ToLower(fmt.Sprint(value)) },
+	"replace":    strings.ReplaceAll,
+}
+
+// Suffixes of the template files in JIRA_TEMPLATE_DIR, the kind of ticket comes in front of them
+const (
+	ticketTemplateSummarySuffix     = "-summary.tmpl"
+	ticketTemplateDescriptionSuffix = "-description.tmpl"
+)
+
+// Sets the templates from the directory given in JIRA_TEMPLATE_DIR
+// Files are named <kind>-summary.tmpl and <kind>-description.tmpl, e.g. evaluation-description.tmpl
+// Every kind is picked up, so tasks (e.g. deployment-summary.tmpl) work the same as the built-in kinds
+func setTicketTemplatesFromDirectory(templates map[string]TicketTemplate) {
+	templateDir := os.Getenv("JIRA_TEMPLATE_DIR")
+	if templateDir == "" {
+		return
+	}
+
+	files, err := ioutil.ReadDir(templateDir)
+	if err != nil {
+		log.Println("[templates.go] Could not read template directory "+templateDir+":", err)
+		return
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		name := file.Name()
+		switch {
+		case strings.HasSuffix(name, ticketTemplateSummarySuffix):
+			kind := strings.TrimSuffix(name, ticketTemplateSummarySuffix)
+			ticketTemplate := templates[kind]
+			ticketTemplate.Summary = readTicketTemplateFile(templateDir, name, ticketTemplate.Summary)
+			templates[kind] = ticketTemplate
+		case strings.HasSuffix(name, ticketTemplateDescriptionSuffix):
+			kind := strings.TrimSuffix(name, ticketTemplateDescriptionSuffix)
+			ticketTemplate := templates[kind]
+			ticketTemplate.Description = readTicketTemplateFile(templateDir, name, ticketTemplate.Description)
+			templates[kind] = ticketTemplate
+		}
+	}
+}
+
+func readTicketTemplateFile(templateDir string, fileName string, fallback string) string {
+	content, err := ioutil.ReadFile(filepath.Join(templateDir, fileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("[templates.go] Could not read template "+fileName+":", err)
+		}
+		return fallback
+	}
+	return string(content)
+}
+
+func newTicketTemplateData(myKeptn *keptnv2.Keptn, data interface{}, summary string, description string) *TicketTemplateData {
+	templateData := &TicketTemplateData{
+		KeptnContext: myKeptn.KeptnContext,
+		BridgeURL:    KEPTN_DETAILS.BridgeURL + "/project/" + myKeptn.Event.GetProject() + "/sequence/" + myKeptn.KeptnContext,
+		Project:      myKeptn.Event.GetProject(),
+		Stage:        myKeptn.Event.GetStage(),
+		Service:      myKeptn.Event.GetService(),
+		Data:         data,
+		Event:        map[string]interface{}{},
+		Summary:      summary,
+		Description:  description,
+	}
+
+	if myKeptn.CloudEvent != nil {
+		templateData.EventType = myKeptn.CloudEvent.Type()
+		if err := myKeptn.CloudEvent.DataAs(&templateData.Event); err != nil {
+			log.Println("[templates.go] Could not decode event data for templates:", err)
+		}
+	}
+
+	return templateData
+}
+
+// Renders the configured templates for the kind of ticket (problem, evaluation, jira, approval or the name of a task)
+// Falls back to the built-in summary and description if no template is configured or rendering fails
+func renderTicketTemplate(kind string, templateData *TicketTemplateData, details *JiraDetails) (string, string) {
+	ticketTemplate := details.Templates[kind]
+
+	summary := renderTemplate(kind+"-summary", ticketTemplate.Summary, templateData, templateData.Summary)
+	description := renderTemplate(kind+"-description", ticketTemplate.Description, templateData, templateData.Description)
+
+	// JIRA doesn't accept line breaks in the summary
+	summary = strings.TrimSpace(strings.ReplaceAll(summary, "\n", " "))
+
+	return summary, description
+}
+
+func renderTemplate(name string, text string, templateData *TicketTemplateData, fallback string) string {
+	if text == "" {
+		return fallback
+	}
+
+	tmpl, err := template.New(name).Funcs(ticketTemplateFuncs).Parse(text)
+	if err != nil {
+		log.Println("[templates.go] Could not parse template "+name+", using built-in text:", err)
+		return fallback
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, templateData); err != nil {
+		log.Println("[templates.go] Could not render template "+name+", using built-in text:", err)
+		return fallback
+	}
+
+	return rendered.String()
+}
+
+/* Add nice JIRA icons
+ * Emojis via API don't follow the UI standard
+ * (/) = :check_mark:
+ * (!) = :warning:
+ * (x) = :cross_mark:
+ */
+func getResultWithIcon(result string) string {
+	if result == "pass" {
+		return result + " (/)"
+	} else if result == "warning" {
+		return result + " (!)"
+	} else if result == "fail" {
+		return result + " (x)"
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// Tasks whose finished events are added to the ticket timeline, unless JIRA_TIMELINE_TASKS is set
+var defaultTimelineTasks = []string{"deployment", "test", keptnv2.EvaluationTaskName, "release", keptnv2.ActionTaskName}
+
+// Returns the task of a sh.keptn.event.<task>.finished event, if the task is part of the timeline
+func getTimelineTask(event cloudevents.Event, details *JiraDetails) (string, bool) {
+	if !details.CommentTimeline || !keptnv2.IsTaskEventType(event.Type()) {
+		return "", false
+	}
+
+	// Never comment our own jira and approval events, the comments could trigger webhook rules in a loop otherwise
+	if event.Source() == ServiceName {
+		return "", false
+	}
+
+	task, kind, _ := keptnv2.ParseTaskEventType(event.Type())
+	if kind != "finished" {
+		return "", false
+	}
+	for _, timelineTask := range details.TimelineTasks {
+		if strings.EqualFold(strings.TrimSpace(timelineTask), task) {
+			return task, true
+		}
+	}
+	return "", false
+}
+
+// Events which create tickets add their details to the ticket of the Keptn context already
+func isTicketEvent(task string, data *keptnv2.EventData, details *JiraDetails) bool {
+	if task == keptnv2.EvaluationTaskName {
+		return details.TicketForEvaluations
+	}
+	if isTicketForTask(task, details) {
+		return isTicketForTaskResult(data.Result)
+	}
+	return details.TicketForAnyTask && isTicketForAnyTaskResult(data, details)
+}
+
+// Adds the result of the task to the open ticket of the Keptn context, so the ticket becomes the log of the sequence
+func HandleTimelineEvent(myKeptn *keptnv2.Keptn, incomingEvent cloudevents.Event, task string, data *keptnv2.EventData, details *JiraDetails) error {
+	log.Printf("[timeline.go] Handling %s event for the ticket timeline: %s", incomingEvent.Type(), incomingEvent.Context.GetID())
+
+	if isTicketEvent(task, data, details) {
+		log.Println("[timeline.go] The " + task + ".finished event creates or comments the ticket itself. Skipping timeline comment")
+		return nil
+	}
+
+	return commentTicketForContext(myKeptn.KeptnContext, createTimelineComment(task, incomingEvent, data), details)
+}
+
+func createTimelineComment(task string, incomingEvent cloudevents.Event, data *keptnv2.EventData) string {
+	comment := "h4. " + task + ".finished: " + getResultWithIcon(string(data.Result)) + "\n"
+	comment += "Time: " + formatEventTime(incomingEvent) + "\n"
+	comment += "Stage: " + data.GetStage() + ", Service: " + data.GetService() + "\n"
+
+	// The score is what matters most about an evaluation
+	if task == keptnv2.EvaluationTaskName {
+		evaluation := &keptnv2.EvaluationFinishedEventData{}
+		if err := incomingEvent.DataAs(evaluation); err == nil {
+			comment += "Score: " + fmt.Sprint(evaluation.Evaluation.Score) + "\n"
+		}
+	}
+
+	if data.Message != "" {
+		comment += "Message: " + data.Message + "\n"
+	}
+	return comment
+}
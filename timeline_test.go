@@ -0,0 +1,192 @@
+package main
+
+import (
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+func TestGetTimelineTask(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		source    string
+		disabled  bool
+		wantTask  string
+		wantOK    bool
+	}{
+		{
+			name:      "finished task of the timeline",
+			eventType: "sh.keptn.event.deployment.finished",
+			source:    "helm-service",
+			wantTask:  "deployment",
+			wantOK:    true,
+		},
+		{
+			name:      "timeline tasks are case insensitive and trimmed",
+			eventType: "sh.keptn.event.test.finished",
+			source:    "jmeter-service",
+			wantTask:  "test",
+			wantOK:    true,
+		},
+		{
+			name:      "timeline is disabled",
+			eventType: "sh.keptn.event.deployment.finished",
+			source:    "helm-service",
+			disabled:  true,
+		},
+		{
+			name:      "triggered task",
+			eventType: "sh.keptn.event.deployment.triggered",
+			source:    "shipyard-controller",
+		},
+		{
+			name:      "started task",
+			eventType: "sh.keptn.event.deployment.started",
+			source:    "helm-service",
+		},
+		{
+			name:      "task which isn't part of the timeline",
+			eventType: "sh.keptn.event.securityscan.finished",
+			source:    "scan-service",
+		},
+		{
+			name:      "finished sequence",
+			eventType: "sh.keptn.event.dev.delivery.finished",
+			source:    "shipyard-controller",
+		},
+		{
+			name:      "problem event",
+			eventType: "sh.keptn.events.problem",
+			source:    "dynatrace",
+		},
+		{
+			name:      "approval finished in the Bridge",
+			eventType: "sh.keptn.event.approval.finished",
+			source:    "https://github.com/keptn/keptn/bridge#approval.finished",
+			wantTask:  "approval",
+			wantOK:    true,
+		},
+		{
+			name:      "own jira task",
+			eventType: "sh.keptn.event.jira.finished",
+			source:    ServiceName,
+		},
+		{
+			name:      "own approval",
+			eventType: "sh.keptn.event.approval.finished",
+			source:    ServiceName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := &JiraDetails{
+				CommentTimeline: !tt.disabled,
+				TimelineTasks:   []string{"deployment", " Test", "evaluation", "jira", "approval"},
+			}
+			event := cloudevents.NewEvent()
+			event.SetType(tt.eventType)
+			event.SetSource(tt.source)
+
+			task, ok := getTimelineTask(event, details)
+			if task != tt.wantTask || ok != tt.wantOK {
+				t.Errorf("getTimelineTask(%s from %s) = %q, %t, want %q, %t", tt.eventType, tt.source, task, ok, tt.wantTask, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsTicketEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    string
+		result  keptnv2.ResultType
+		status  keptnv2.StatusType
+		details *JiraDetails
+		want    bool
+	}{
+		{
+			name:    "failed task with tickets",
+			task:    "deployment",
+			result:  keptnv2.ResultFailed,
+			details: &JiraDetails{TicketForTasks: []string{"deployment", "test"}},
+			want:    true,
+		},
+		{
+			name:    "task with tickets finished with warning",
+			task:    "test",
+			result:  keptnv2.ResultWarning,
+			details: &JiraDetails{TicketForTasks: []string{"deployment", "test"}},
+			want:    true,
+		},
+		{
+			name:    "passed task with tickets",
+			task:    "deployment",
+			result:  keptnv2.ResultPass,
+			details: &JiraDetails{TicketForTasks: []string{"deployment", "test"}},
+			want:    false,
+		},
+		{
+			name:    "failed task without tickets",
+			task:    "release",
+			result:  keptnv2.ResultFailed,
+			details: &JiraDetails{TicketForTasks: []string{"deployment", "test"}},
+			want:    false,
+		},
+		{
+			name:    "evaluation with ticketForEvaluations",
+			task:    keptnv2.EvaluationTaskName,
+			result:  keptnv2.ResultPass,
+			details: &JiraDetails{TicketForEvaluations: true},
+			want:    true,
+		},
+		{
+			name:    "evaluation without ticketForEvaluations",
+			task:    keptnv2.EvaluationTaskName,
+			result:  keptnv2.ResultFailed,
+			details: &JiraDetails{TicketForAnyTask: true, TicketForResults: []string{"fail"}},
+			want:    false,
+		},
+		{
+			name:    "failed task of the catch-all",
+			task:    "securityscan",
+			result:  keptnv2.ResultFailed,
+			details: &JiraDetails{TicketForAnyTask: true, TicketForResults: []string{"fail"}},
+			want:    true,
+		},
+		{
+			name:    "catch-all matches the status",
+			task:    "securityscan",
+			result:  keptnv2.ResultPass,
+			status:  keptnv2.StatusErrored,
+			details: &JiraDetails{TicketForAnyTask: true, TicketForResults: []string{"fail", " errored"}},
+			want:    true,
+		},
+		{
+			name:    "passed task of the catch-all",
+			task:    "securityscan",
+			result:  keptnv2.ResultPass,
+			status:  keptnv2.StatusSucceeded,
+			details: &JiraDetails{TicketForAnyTask: true, TicketForResults: []string{"fail"}},
+			want:    false,
+		},
+		{
+			name:    "catch-all is disabled",
+			task:    "securityscan",
+			result:  keptnv2.ResultFailed,
+			details: &JiraDetails{TicketForResults: []string{"fail"}},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &keptnv2.EventData{Result: tt.result, Status: tt.status}
+			if got := isTicketEvent(tt.task, data, tt.details); got != tt.want {
+				t.Errorf("isTicketEvent(%s, %s, %s) = %t, want %t", tt.task, tt.result, tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Issue trackers the jira-service can create tickets in, selected with TRACKER or tracker in jira.yaml
+const (
+	trackerJIRA   = "jira"
+	trackerGitHub = "github"
+)
+
+// Ticket is a ticket independent of the issue tracker it lives in
+// Descriptions and comments are always written in JIRA wiki markup, trackers with another markup convert them
+type Ticket struct {
+	// Key identifies the ticket in the tracker, e.g. KEP-12 in JIRA or owner/repo#12 in GitHub
+	Key string `json:"key,omitempty"`
+	// URL links to the ticket in the UI of the tracker
+	URL string `json:"url,omitempty"`
+	// Project is the JIRA project key or the GitHub repository (owner/repo)
+	Project string `json:"project,omitempty"`
+	// IssueType is only used by JIRA
+	IssueType string `json:"issueType,omitempty"`
+	// Assignee and Reporter are JIRA account IDs or GitHub logins
+	Assignee string `json:"assignee,omitempty"`
+	Reporter string `json:"reporter,omitempty"`
+	// Priority is the name or ID of a JIRA priority, empty for the default priority
+	Priority    string   `json:"priority,omitempty"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels,omitempty"`
+	// Components and versions are only used by JIRA
+	Components      []string `json:"components,omitempty"`
+	FixVersions     []string `json:"fixVersions,omitempty"`
+	AffectsVersions []string `json:"affectsVersions,omitempty"`
+	// CustomFields are set as they are, keyed by the ID of the JIRA custom field
+	CustomFields map[string]interface{} `json:"customFields,omitempty"`
+	Status       string                 `json:"status,omitempty"`
+	// Attachments are uploaded after the ticket is created, trackers without attachments skip them
+	Attachments []TicketAttachment `json:"attachments,omitempty"`
+}
+
+// Tracker is what the jira-service needs from an issue tracker
+type Tracker interface {
+	// Name of the tracker, e.g. jira
+	Name() string
+	// CreateTicket fills the fields that aren't set with the defaults of the tracker,
+	// creates the ticket and sets its key and URL. The assignee is resolved before, see resolveTicketAssignee
+	CreateTicket(ticket *Ticket) error
+	// UpdateLabels adds and removes labels of a ticket
+	UpdateLabels(key string, add []string, remove []string) error
+	// AddComment adds a comment to a ticket
+	AddComment(key string, body string) error
+	// Transition moves a ticket through the given transition or into the given status
+	Transition(key string, transition string) error
+	// GetTicket returns the ticket with its current status and labels
+	GetTicket(key string) (*Ticket, error)
+	// FindOpenTicket returns the newest ticket with the label which is not done yet, or nil if there is none
+	// Tickets might have been routed to another project, so JIRA searches in all projects and GitHub in all routed repositories
+	FindOpenTicket(label string) (*Ticket, error)
+}
+
+// Creates the tracker configured in the settings of the event
+func newTracker(details *JiraDetails) (Tracker, error) {
+	switch strings.ToLower(details.Tracker) {
+	case "", trackerJIRA:
+		return newJIRATracker(details)
+	case trackerGitHub:
+		return newGitHubTracker(details)
+	}
+	return nil, &TrackerClientError{Tracker: details.Tracker, Err: errors.New("unknown tracker")}
+}
+
+// Returns true if the tracker of the event is JIRA, which is the default
+func isJIRATracker(details *JiraDetails) bool {
+	return details.Tracker == "" || strings.EqualFold(details.Tracker, trackerJIRA)
+}
+
+// Creates the tracker with the settings from the environment, e.g. for retries of the outbox which aren't bound to an event
+func newTrackerByName(name string) (Tracker, error) {
+	details := JIRA_DETAILS
+	details.Tracker = name
+	return newTracker(&details)
+}
+
+// Network errors, rate limits and server errors of the tracker are worth a retry, other errors are not
+func isRetryableTrackerError(err error) bool {
+	var requestError *TrackerRequestError
+	if !errors.As(err, &requestError) {
+		return false
+	}
+	return requestError.StatusCode == 0 || isRetryableStatusCode(requestError.StatusCode)
+}
+
+// Returns the names of the missing settings of the configured tracker
+func getMissingTrackerSettings(details *JiraDetails) []string {
+	missing := []string{}
+	switch strings.ToLower(details.Tracker) {
+	case trackerGitHub:
+		if details.GitHub.Token == "" {
+			missing = append(missing, "GITHUB_TOKEN")
+		}
+		if details.GitHub.Repository == "" {
+			missing = append(missing, "GITHUB_REPOSITORY")
+		}
+	default:
+		if details.BaseURL == "" {
+			missing = append(missing, "JIRA_BASE_URL")
+		}
+		// Only basic auth needs a username
+		if details.Username == "" && strings.EqualFold(details.AuthType, jiraAuthBasic) {
+			missing = append(missing, "JIRA_USERNAME")
+		}
+		missing = append(missing, getMissingJIRAAuthSettings(details)...)
+		if details.ProjectKey == "" {
+			missing = append(missing, "JIRA_PROJECT_KEY")
+		}
+		if details.IssueType == "" {
+			missing = append(missing, "JIRA_ISSUE_TYPE")
+		}
+	}
+	return missing
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// JIRA fields the artifact version can be written to, selected with JIRA_VERSION_FIELD or versionField in jira.yaml
+const (
+	jiraVersionFieldFix     = "fixVersions"
+	jiraVersionFieldAffects = "affectsVersions"
+)
+
+// Label of the event which holds the artifact version, unless JIRA_VERSION_LABEL is set
+const defaultVersionLabel = "version"
+
+// Sets the Keptn service as component and the artifact version as fix or affects version, if enabled
+// Routing rules are applied afterwards and can add further components
+func setTicketComponentsAndVersions(ticket *Ticket, templateData *TicketTemplateData, details *JiraDetails) {
+	if details.ServiceComponent && templateData.Service != "" {
+		ticket.Components = appendUnique(ticket.Components, templateData.Service)
+	}
+
+	if details.VersionField == "" {
+		return
+	}
+	version := getArtifactVersion(templateData, details)
+	if version == "" {
+		log.Println("[versions.go] No artifact version found in the event, not setting " + details.VersionField)
+		return
+	}
+
+	switch {
+	case strings.EqualFold(details.VersionField, jiraVersionFieldFix):
+		ticket.FixVersions = appendUnique(ticket.FixVersions, version)
+	case strings.EqualFold(details.VersionField, jiraVersionFieldAffects):
+		ticket.AffectsVersions = appendUnique(ticket.AffectsVersions, version)
+	default:
+		log.Println("[versions.go] Unknown version field " + details.VersionField + ", use " + jiraVersionFieldFix + " or " + jiraVersionFieldAffects)
+	}
+}
+
+// The version label of the event wins, otherwise the tag of the deployed image is used
+func getArtifactVersion(templateData *TicketTemplateData, details *JiraDetails) string {
+	label := details.VersionLabel
+	if label == "" {
+		label = defaultVersionLabel
+	}
+	labels, _ := templateData.Event["labels"].(map[string]interface{})
+	if version, ok := labels[label]; ok && fmt.Sprint(version) != "" {
+		return fmt.Sprint(version)
+	}
+
+	// deployment.triggered and the events of the delivery sequence carry the image which is deployed
+	if image, ok := resolveCustomFieldSource(templateData.Event, "configurationChange.values.image"); ok {
+		return getImageTag(fmt.Sprint(image))
+	}
+	return ""
+}
+
+// Returns the tag of an image like docker.io/keptnexamples/carts:0.12.1, the port of a registry is no tag
+func getImageTag(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
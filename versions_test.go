@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetArtifactVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        string
+		versionLabel string
+		want         string
+	}{
+		{
+			name:  "version label",
+			event: `{"labels": {"version": "1.2.3"}, "configurationChange": {"values": {"image": "keptnexamples/carts:0.12.1"}}}`,
+			want:  "1.2.3",
+		},
+		{
+			name:  "numeric version label",
+			event: `{"labels": {"version": 2}}`,
+			want:  "2",
+		},
+		{
+			name:         "configured label",
+			event:        `{"labels": {"version": "1.2.3", "buildVersion": "1.2.4"}}`,
+			versionLabel: "buildVersion",
+			want:         "1.2.4",
+		},
+		{
+			name:         "missing configured label falls back to the image tag",
+			event:        `{"labels": {"version": "1.2.3"}, "configurationChange": {"values": {"image": "keptnexamples/carts:0.12.1"}}}`,
+			versionLabel: "buildVersion",
+			want:         "0.12.1",
+		},
+		{
+			name:  "empty label falls back to the image tag",
+			event: `{"labels": {"version": ""}, "configurationChange": {"values": {"image": "docker.io/keptnexamples/carts:0.12.1"}}}`,
+			want:  "0.12.1",
+		},
+		{
+			name:  "port of the registry is no tag",
+			event: `{"configurationChange": {"values": {"image": "localhost:5000/carts:0.12.1"}}}`,
+			want:  "0.12.1",
+		},
+		{
+			name:  "image without tag",
+			event: `{"configurationChange": {"values": {"image": "localhost:5000/carts"}}}`,
+			want:  "",
+		},
+		{
+			name:  "image with digest",
+			event: `{"configurationChange": {"values": {"image": "keptnexamples/carts:0.12.1@sha256:4a1c4b21597c1b4415bdbecb28a3296c6b5e23ca4f9feeb599860a1dac6a0108"}}}`,
+			want:  "0.12.1",
+		},
+		{
+			name:  "event without labels and image",
+			event: `{"project": "sockshop"}`,
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(tt.event), &event); err != nil {
+				t.Fatalf("could not decode event: %s", err)
+			}
+
+			got := getArtifactVersion(&TicketTemplateData{Event: event}, &JiraDetails{VersionLabel: tt.versionLabel})
+			if got != tt.want {
+				t.Errorf("getArtifactVersion(%s) = %q, want %q", tt.event, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTicketComponentsAndVersions(t *testing.T) {
+	templateData := &TicketTemplateData{Service: "carts", Event: map[string]interface{}{"labels": map[string]interface{}{"version": "1.2.3"}}}
+
+	tests := []struct {
+		name                string
+		details             *JiraDetails
+		wantComponents      []string
+		wantFixVersions     []string
+		wantAffectsVersions []string
+	}{
+		{
+			name:            "service as component and fix version",
+			details:         &JiraDetails{ServiceComponent: true, VersionField: "fixVersions"},
+			wantComponents:  []string{"carts"},
+			wantFixVersions: []string{"1.2.3"},
+		},
+		{
+			name:                "version field is case insensitive",
+			details:             &JiraDetails{VersionField: "AffectsVersions"},
+			wantAffectsVersions: []string{"1.2.3"},
+		},
+		{
+			name:    "unknown version field",
+			details: &JiraDetails{VersionField: "versions"},
+		},
+		{
+			name:    "disabled",
+			details: &JiraDetails{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket := &Ticket{}
+			setTicketComponentsAndVersions(ticket, templateData, tt.details)
+
+			if !stringSlicesEqual(ticket.Components, tt.wantComponents) {
+				t.Errorf("Components = %q, want %q", ticket.Components, tt.wantComponents)
+			}
+			if !stringSlicesEqual(ticket.FixVersions, tt.wantFixVersions) {
+				t.Errorf("FixVersions = %q, want %q", ticket.FixVersions, tt.wantFixVersions)
+			}
+			if !stringSlicesEqual(ticket.AffectsVersions, tt.wantAffectsVersions) {
+				t.Errorf("AffectsVersions = %q, want %q", ticket.AffectsVersions, tt.wantAffectsVersions)
+			}
+		})
+	}
+}
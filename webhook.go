@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2" // make sure to use v2 cloudevents here
+	"github.com/google/uuid"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// WebhookRule maps a JIRA webhook event to a Keptn event
+// Rules are configured in jira.yaml, the project, stage and service are taken from the labels of the ticket
+type WebhookRule struct {
+	// WebhookEvent is the JIRA webhook event, e.g. jira:issue_updated or comment_created
+	WebhookEvent string `yaml:"webhookEvent"`
+	// Status matches the new status of the ticket, e.g. Rollback requested
+	Status string `yaml:"status"`
+	// Comment matches comments which start with the text, e.g. /rollback
+	Comment string `yaml:"comment"`
+	// Sequence is triggered in the stage of the ticket, e.g. remediation for sh.keptn.event.<stage>.remediation.triggered
+	Sequence string `yaml:"sequence"`
+	// EventType is sent as is, if no sequence is set
+	EventType string `yaml:"eventType"`
+	// Stage overrides the stage of the ticket
+	Stage string `yaml:"stage"`
+}
+
+// Receives the webhooks JIRA sends for issue events
+// The webhook is served next to the CloudEvents receiver, see _main
+func handleJIRAWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// JIRA can't sign webhooks on every deployment type, so a shared secret is passed as query parameter
+	// Webhooks finish approvals and trigger sequences, so they are refused as long as no secret is configured
+	secret := os.Getenv("JIRA_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Println("[webhook.go] Rejected JIRA webhook, JIRA_WEBHOOK_SECRET is not set")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(secret)) != 1 {
+		log.Println("[webhook.go] Rejected JIRA webhook with invalid secret")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	webhookEvent := &JiraWebhookEvent{}
+	if err := json.NewDecoder(r.Body).Decode(webhookEvent); err != nil {
+		log.Println("[webhook.go] Could not decode JIRA webhook:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	log.Println("[webhook.go] Received JIRA webhook: " + webhookEvent.WebhookEvent)
+	processJIRAWebhookEvent(webhookEvent)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func processJIRAWebhookEvent(webhookEvent *JiraWebhookEvent) {
+	if webhookEvent.Issue == nil {
+		return
+	}
+
+	if webhookEvent.WebhookEvent == "jira:issue_updated" && isJIRAStatusChange(webhookEvent) {
+		handleApprovalStatusChange(webhookEvent)
+	}
+
+	handleWebhookRules(webhookEvent)
+}
+
+// Sends a Keptn event for the first webhook rule in jira.yaml that matches the webhook event
+func handleWebhookRules(webhookEvent *JiraWebhookEvent) {
+	issue := webhookEvent.Issue
+	if issue.Fields == nil {
+		return
+	}
+
+	// Only tickets created by the jira-service carry the project label
+	eventData := getKeptnEventDataFromJIRALabels(issue.Fields.Labels)
+	if eventData.Project == "" {
+		return
+	}
+
+	myKeptn, err := newKeptnHandlerForJIRATicket(eventData)
+	if err != nil {
+		log.Println("[webhook.go] Could not create Keptn Handler:", err)
+		return
+	}
+
+	details := loadJIRADetails(myKeptn)
+
+	for _, rule := range details.WebhookRules {
+		if !rule.matches(webhookEvent) {
+			continue
+		}
+
+		eventType := rule.EventType
+		stage := eventData.Stage
+		if rule.Stage != "" {
+			stage = rule.Stage
+		}
+		if rule.Sequence != "" {
+			eventType = keptnv2.GetTriggeredEventType(stage + "." + rule.Sequence)
+		}
+		if eventType == "" {
+			log.Println("[webhook.go] Webhook rule has neither a sequence nor an event type")
+			return
+		}
+
+		sendKeptnEventForJIRATicket(myKeptn, webhookEvent, eventType, stage, details)
+		return
+	}
+}
+
+func (r *WebhookRule) matches(webhookEvent *JiraWebhookEvent) bool {
+	// JIRA prefixes issue events with jira:, but not comment events
+	if r.WebhookEvent != "" && strings.TrimPrefix(r.WebhookEvent, "jira:") != strings.TrimPrefix(webhookEvent.WebhookEvent, "jira:") {
+		return false
+	}
+
+	if r.Status != "" {
+		if !isJIRAStatusChange(webhookEvent) || webhookEvent.Issue.Fields.Status == nil ||
+			!strings.EqualFold(webhookEvent.Issue.Fields.Status.Name, r.Status) {
+			return false
+		}
+	}
+
+	if r.Comment != "" {
+		if webhookEvent.Comment == nil || !strings.HasPrefix(strings.TrimSpace(webhookEvent.Comment.Body), r.Comment) {
+			return false
+		}
+	}
+
+	return r.Status != "" || r.Comment != ""
+}
+
+// Triggers a new Keptn context for the event and links it to the ticket
+func sendKeptnEventForJIRATicket(myKeptn *keptnv2.Keptn, webhookEvent *JiraWebhookEvent, eventType string, stage string, details *JiraDetails) {
+	issue := webhookEvent.Issue
+	keptnContext := uuid.New().String()
+	issueURL := strings.TrimSuffix(details.SiteURL, "/") + "/browse/" + issue.Key
+
+	message := "Triggered by JIRA ticket " + issue.Key
+	if webhookEvent.User != nil && webhookEvent.User.DisplayName != "" {
+		message += " (" + webhookEvent.User.DisplayName + ")"
+	}
+
+	labels := myKeptn.Event.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels["jiraIssue"] = issue.Key
+
+	data := &JiraTriggeredEventData{
+		EventData: keptnv2.EventData{
+			Project: myKeptn.Event.GetProject(),
+			Stage:   stage,
+			Service: myKeptn.Event.GetService(),
+			Labels:  labels,
+			Message: message,
+		},
+		Jira: JiraTaskData{
+			IssueKey: issue.Key,
+			IssueURL: issueURL,
+		},
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.New().String())
+	event.SetType(eventType)
+	event.SetSource(ServiceName)
+	event.SetExtension("shkeptncontext", keptnContext)
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		log.Println("[webhook.go] Could not set data of event:", err)
+		return
+	}
+
+	if err := myKeptn.SendCloudEvent(event); err != nil {
+		log.Println("[webhook.go] Could not send "+eventType+":", err)
+		return
+	}
+	log.Println("[webhook.go] Sent " + eventType + " for JIRA ticket " + issue.Key + " with Keptn context " + keptnContext)
+
+	tracker, err := newTracker(details)
+	if err != nil {
+		log.Println("[webhook.go] Could not create tracker client:", err)
+		return
+	}
+
+	// Label the ticket with the new context, so that events of the new sequence find it
+	if err := tracker.UpdateLabels(issue.Key, []string{createJIRAContextLabel(keptnContext)}, nil); err != nil {
+		log.Println("[webhook.go] Could not update labels of ticket", issue.Key, ":", err)
+	}
+
+	bridgeURL := KEPTN_DETAILS.BridgeURL + "/project/" + myKeptn.Event.GetProject() + "/sequence/" + keptnContext
+	addTicketComment(tracker, issue.Key, "Triggered "+eventType+" in Keptn. [Link To Keptn's Bridge|"+bridgeURL+"]")
+}
+
+// Builds a Keptn Handler for the project, stage and service of a ticket
+func newKeptnHandlerForJIRATicket(eventData *keptnv2.EventData) (*keptnv2.Keptn, error) {
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.New().String())
+	event.SetType("sh.keptn.event.jira.webhook")
+	event.SetSource(ServiceName)
+	event.SetExtension("shkeptncontext", "")
+	if err := event.SetData(cloudevents.ApplicationJSON, eventData); err != nil {
+		return nil, err
+	}
+
+	return keptnv2.NewKeptn(&event, keptnOptions)
+}
+
+// Reads project, stage, service and the JIRA label back from the labels the jira-service adds to its tickets
+func getKeptnEventDataFromJIRALabels(labels []string) *keptnv2.EventData {
+	return &keptnv2.EventData{
+		Project: getJIRALabelValue(labels, "keptn_project:"),
+		Stage:   getJIRALabelValue(labels, "keptn_stage:"),
+		Service: getJIRALabelValue(labels, "keptn_service:"),
+	}
+}